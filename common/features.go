@@ -0,0 +1,30 @@
+package common
+
+// Feature is an optional feature of a database which changes the SQL built
+// by dat. See dat.SQLDialect.
+type Feature int
+
+const (
+	// FeatureReturning is the RETURNING clause of INSERT and UPDATE.
+	FeatureReturning Feature = iota
+	// FeatureWritableCTE is a WITH clause containing INSERT or UPDATE, used
+	// by Upsert and Insect.
+	FeatureWritableCTE
+	// FeatureOnDuplicateKeyUpdate is the ON DUPLICATE KEY UPDATE clause of
+	// INSERT, used by Upsert when FeatureWritableCTE is not supported.
+	FeatureOnDuplicateKeyUpdate
+	// FeatureUpdateOffset is the OFFSET clause of UPDATE.
+	FeatureUpdateOffset
+	// FeatureJSONAggregate is TO_JSON(ARRAY_AGG(...)), used by QueryJSON and
+	// QueryObject to return the rows as a JSON array.
+	FeatureJSONAggregate
+	// FeatureSelectDoc is row_to_json and nested ARRAY_AGG, used by
+	// SelectDoc.
+	FeatureSelectDoc
+	// FeatureCancel is the cancellation of a running statement with
+	// pg_cancel_backend, used by Timeout.
+	FeatureCancel
+	// FeatureDeferrable is SET TRANSACTION DEFERRABLE, used by
+	// TxOptions.Deferrable.
+	FeatureDeferrable
+)
@@ -0,0 +1,171 @@
+package common
+
+import "strings"
+
+// Identifier is a parsed column or table name such as `name`,
+// `table.column`, `schema.table alias` or `table AS alias`.
+type Identifier struct {
+	Parts []IdentifierPart
+	Alias *IdentifierPart
+}
+
+// IdentifierPart is a part of an identifier. Quoted parts were written with
+// " or ` and are case sensitive.
+type IdentifierPart struct {
+	Name   string
+	Quoted bool
+}
+
+// ParseIdentifier parses s as an optionally qualified identifier followed by
+// an optional alias. The last part may be *, as in `table.*`. It returns
+// false if s is anything else, such as an expression.
+func ParseIdentifier(s string) (*Identifier, bool) {
+	p := identifierParser{s: s}
+	id := &Identifier{}
+
+	p.skipSpaces()
+	for {
+		if p.peek() == '*' {
+			p.i++
+			id.Parts = append(id.Parts, IdentifierPart{Name: "*"})
+			break
+		}
+		part, ok := p.part()
+		if !ok {
+			return nil, false
+		}
+		id.Parts = append(id.Parts, part)
+		if p.peek() != '.' {
+			break
+		}
+		p.i++
+	}
+
+	hasSpace := p.skipSpaces()
+	if p.done() {
+		return id, true
+	}
+	if !hasSpace || id.Parts[len(id.Parts)-1].Name == "*" {
+		return nil, false
+	}
+	if start := p.i; p.keyword("as") && p.skipSpaces() {
+		// AS alias
+	} else {
+		p.i = start
+	}
+	alias, ok := p.part()
+	if !ok {
+		return nil, false
+	}
+	id.Alias = &alias
+	p.skipSpaces()
+	if !p.done() {
+		return nil, false
+	}
+	return id, true
+}
+
+// Write writes the identifier, quoting with quote the quoted parts and the
+// parts for which needsQuote returns true.
+func (id *Identifier) Write(buf BufferWriter, quote rune, needsQuote func(part string) bool) {
+	for i, part := range id.Parts {
+		if i > 0 {
+			buf.WriteRune('.')
+		}
+		part.write(buf, quote, needsQuote)
+	}
+	if id.Alias != nil {
+		buf.WriteRune(' ')
+		id.Alias.write(buf, quote, needsQuote)
+	}
+}
+
+func (part IdentifierPart) write(buf BufferWriter, quote rune, needsQuote func(part string) bool) {
+	if part.Name == "*" || (!part.Quoted && !needsQuote(part.Name)) {
+		buf.WriteString(part.Name)
+		return
+	}
+	q := string(quote)
+	buf.WriteRune(quote)
+	buf.WriteString(strings.Replace(part.Name, q, q+q, -1))
+	buf.WriteRune(quote)
+}
+
+// WriteQuotedIdentifier writes ident with each part quoted with quote. Anything
+// other than an identifier is written as is since dat lets expressions be
+// passed as identifiers. See ParseIdentifier.
+func WriteQuotedIdentifier(buf BufferWriter, ident string, quote rune) {
+	id, ok := ParseIdentifier(ident)
+	if !ok {
+		buf.WriteString(ident)
+		return
+	}
+	id.Write(buf, quote, func(string) bool { return true })
+}
+
+type identifierParser struct {
+	s string
+	i int
+}
+
+func (p *identifierParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *identifierParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *identifierParser) skipSpaces() bool {
+	start := p.i
+	for !p.done() && (p.s[p.i] == ' ' || p.s[p.i] == '\t' || p.s[p.i] == '\n' || p.s[p.i] == '\r') {
+		p.i++
+	}
+	return p.i > start
+}
+
+// keyword consumes word, ignoring case, if it is followed by a space.
+func (p *identifierParser) keyword(word string) bool {
+	end := p.i + len(word)
+	if end >= len(p.s) || !strings.EqualFold(p.s[p.i:end], word) || isIdentifierByte(p.s[end]) {
+		return false
+	}
+	p.i = end
+	return true
+}
+
+func (p *identifierParser) part() (IdentifierPart, bool) {
+	c := p.peek()
+	if c == '"' || c == '`' {
+		var name strings.Builder
+		for j := p.i + 1; j < len(p.s); j++ {
+			if p.s[j] != c {
+				name.WriteByte(p.s[j])
+				continue
+			}
+			if j+1 < len(p.s) && p.s[j+1] == c {
+				name.WriteByte(c)
+				j++
+				continue
+			}
+			if name.Len() == 0 {
+				return IdentifierPart{}, false
+			}
+			p.i = j + 1
+			return IdentifierPart{Name: name.String(), Quoted: true}, true
+		}
+		return IdentifierPart{}, false
+	}
+
+	if c == 0 || isDigit(c) || c == '$' || !isIdentifierByte(c) {
+		return IdentifierPart{}, false
+	}
+	start := p.i
+	for !p.done() && isIdentifierByte(p.s[p.i]) {
+		p.i++
+	}
+	return IdentifierPart{Name: p.s[start:p.i]}, true
+}
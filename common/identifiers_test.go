@@ -0,0 +1,46 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/nerdynz/dat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIdentifier(t *testing.T) {
+	valid := []string{
+		"name",
+		"people.name",
+		"public.people p",
+		"public.people AS p",
+		"people.*",
+		"*",
+		`"Mixed Case"."col"`,
+		"`backticks`",
+		`"a""b"`,
+		"naïve",
+	}
+	for _, s := range valid {
+		_, ok := common.ParseIdentifier(s)
+		assert.True(t, ok, s)
+	}
+
+	invalid := []string{
+		"",
+		"1abc",
+		"count(*)",
+		"name; DROP TABLE people",
+		"id = 1 OR 1=1 --",
+		"people.",
+		".name",
+		"people p q",
+		"people.* p",
+		`"unterminated`,
+		`""`,
+		"name--",
+	}
+	for _, s := range invalid {
+		_, ok := common.ParseIdentifier(s)
+		assert.False(t, ok, s)
+	}
+}
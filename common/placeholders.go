@@ -0,0 +1,82 @@
+package common
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// ReplacePlaceholders calls write for each $1, $2 ... placeholder of sql
+// with its position, writing the rest of sql as is. String literals, quoted
+// identifiers and comments are not searched.
+func ReplacePlaceholders(sql string, write func(buf BufferWriter, pos int)) string {
+	return replacePlaceholders(sql, false, write)
+}
+
+// ReplacePlaceholdersBackslash is ReplacePlaceholders for dialects such as
+// MySQL where a backslash also escapes a quote within string literals.
+func ReplacePlaceholdersBackslash(sql string, write func(buf BufferWriter, pos int)) string {
+	return replacePlaceholders(sql, true, write)
+}
+
+func replacePlaceholders(sql string, backslash bool, write func(buf BufferWriter, pos int)) string {
+	var buf bytes.Buffer
+	buf.Grow(len(sql))
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(sql, i, backslash && c != '`')
+			buf.WriteString(sql[i:end])
+			i = end
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := i
+			for end < len(sql) && sql[end] != '\n' {
+				end++
+			}
+			buf.WriteString(sql[i:end])
+			i = end
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]) && (i == 0 || !isIdentifierByte(sql[i-1])):
+			end := i + 1
+			for end < len(sql) && isDigit(sql[end]) {
+				end++
+			}
+			pos, _ := strconv.Atoi(sql[i+1 : end])
+			write(&buf, pos)
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
+
+// closingQuote returns the index after the quoted section starting at i. A
+// doubled quote escapes the quote, as does a backslash if backslash is true.
+func closingQuote(sql string, i int, backslash bool) int {
+	quote := sql[i]
+	for j := i + 1; j < len(sql); j++ {
+		if backslash && sql[j] == '\\' {
+			j++
+			continue
+		}
+		if sql[j] != quote {
+			continue
+		}
+		if j+1 < len(sql) && sql[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(sql)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
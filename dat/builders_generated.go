@@ -4,7 +4,13 @@ package dat
 
 // Interpolate interpolates this builders sql.
 func (b *CallBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *CallBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -19,9 +25,26 @@ func (b *CallBuilder) SetIsInterpolated(enable bool) *CallBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *CallBuilder) WithDialect(dialect SQLDialect) *CallBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *CallBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
 // Interpolate interpolates this builders sql.
 func (b *DeleteBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -36,9 +59,34 @@ func (b *DeleteBuilder) SetIsInterpolated(enable bool) *DeleteBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *DeleteBuilder) WithDialect(dialect SQLDialect) *DeleteBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *DeleteBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *DeleteBuilder) WithStrictIdentifiers() *DeleteBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *InsectBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -53,9 +101,34 @@ func (b *InsectBuilder) SetIsInterpolated(enable bool) *InsectBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *InsectBuilder) WithDialect(dialect SQLDialect) *InsectBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *InsectBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *InsectBuilder) WithStrictIdentifiers() *InsectBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *InsertBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -70,9 +143,34 @@ func (b *InsertBuilder) SetIsInterpolated(enable bool) *InsertBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *InsertBuilder) WithDialect(dialect SQLDialect) *InsertBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *InsertBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *InsertBuilder) WithStrictIdentifiers() *InsertBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *RawBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *RawBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -87,9 +185,26 @@ func (b *RawBuilder) SetIsInterpolated(enable bool) *RawBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *RawBuilder) WithDialect(dialect SQLDialect) *RawBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *RawBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
 // Interpolate interpolates this builders sql.
 func (b *SelectBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -104,9 +219,34 @@ func (b *SelectBuilder) SetIsInterpolated(enable bool) *SelectBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *SelectBuilder) WithDialect(dialect SQLDialect) *SelectBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *SelectBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *SelectBuilder) WithStrictIdentifiers() *SelectBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *SelectDocBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -121,9 +261,34 @@ func (b *SelectDocBuilder) SetIsInterpolated(enable bool) *SelectDocBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *SelectDocBuilder) WithDialect(dialect SQLDialect) *SelectDocBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *SelectDocBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *SelectDocBuilder) WithStrictIdentifiers() *SelectDocBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *UpdateBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -138,9 +303,34 @@ func (b *UpdateBuilder) SetIsInterpolated(enable bool) *UpdateBuilder {
 	return b
 }
 
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *UpdateBuilder) WithDialect(dialect SQLDialect) *UpdateBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *UpdateBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *UpdateBuilder) WithStrictIdentifiers() *UpdateBuilder {
+	b.strictIdentifiers = true
+	return b
+}
+
 // Interpolate interpolates this builders sql.
 func (b *UpsertBuilder) Interpolate() (string, []interface{}, error) {
-	return interpolate(b)
+	return interpolate(b, b.dialect)
+}
+
+// ToSQL serializes this builder to SQL with the placeholders of its dialect
+// and a slice of query arguments.
+func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
+	return toSQL(b, b.dialect)
 }
 
 // IsInterpolated determines if this builder will interpolate when
@@ -154,3 +344,22 @@ func (b *UpsertBuilder) SetIsInterpolated(enable bool) *UpsertBuilder {
 	b.isInterpolated = enable
 	return b
 }
+
+// WithDialect sets the dialect this builder's SQL is written in.
+func (b *UpsertBuilder) WithDialect(dialect SQLDialect) *UpsertBuilder {
+	b.dialect = dialect
+	return b
+}
+
+// SetDialect sets the dialect this builder's SQL is written in.
+func (b *UpsertBuilder) SetDialect(dialect SQLDialect) {
+	b.dialect = dialect
+}
+
+// WithStrictIdentifiers makes this builder return ErrInvalidIdentifier when
+// its table, Eq keys, Set, Columns, Whitelist or Returning columns are not
+// identifiers. Select columns, OrderBy and SQL fragments are not checked.
+func (b *UpsertBuilder) WithStrictIdentifiers() *UpsertBuilder {
+	b.strictIdentifiers = true
+	return b
+}
@@ -5,6 +5,7 @@ import "github.com/nerdynz/dat/internal/log"
 // CallBuilder is a store procedure call builder.
 type CallBuilder struct {
 	Execer
+	dialect SQLDialect
 
 	args           []interface{}
 	isInterpolated bool
@@ -20,9 +21,9 @@ func NewCallBuilder(sproc string, args ...interface{}) *CallBuilder {
 	return &CallBuilder{sproc: sproc, args: args, isInterpolated: EnableInterpolation}
 }
 
-// ToSQL serializes CallBuilder to a SQL string returning
-// valid SQL with placeholders an a slice of query arguments.
-func (b *CallBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serializes CallBuilder to a SQL string returning
+// valid SQL with $1, $2 ... placeholders an a slice of query arguments.
+func (b *CallBuilder) toSQL() (string, []interface{}, error) {
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 
@@ -5,6 +5,8 @@ import "github.com/nerdynz/dat/internal/log"
 // DeleteBuilder contains the clauses for a DELETE statement
 type DeleteBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	table          string
 	whereFragments []*whereFragment
@@ -31,7 +33,7 @@ func (b *DeleteBuilder) ScopeMap(mapScope *MapScope, m M) *DeleteBuilder {
 // Scope uses a predefined scope in place of WHERE.
 func (b *DeleteBuilder) Scope(sql string, args ...interface{}) *DeleteBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
-		return escapeScopeTable(sql, table), args
+		return escapeScopeTable(dialectOrDefault(b.dialect), sql, table), args
 	})
 	return b
 }
@@ -48,9 +50,10 @@ func (b *DeleteBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *D
 	return b
 }
 
-// ToSQL serialized the DeleteBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serialized the DeleteBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *DeleteBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
@@ -58,6 +61,9 @@ func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
 	if len(b.table) == 0 {
 		return NewDatSQLError("no table specified")
 	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -65,7 +71,7 @@ func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
 	var args []interface{}
 
 	buf.WriteString("DELETE FROM ")
-	buf.WriteString(b.table)
+	dialect.WriteIdentifier(buf, b.table)
 
 	var placeholderStartPos int64 = 1
 
@@ -73,10 +79,12 @@ func (b *DeleteBuilder) ToSQL() (string, []interface{}, error) {
 	if b.scope == nil {
 		if len(b.whereFragments) > 0 {
 			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.whereFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 	} else {
-		whereFragment, err := newWhereFragment(b.scope.ToSQL(b.table))
+		whereFragment, err := newWhereFragment(scopeToSQL(dialect, b.scope, b.table))
 		if err != nil {
 			return NewDatSQLErr(err)
 		}
@@ -4,10 +4,13 @@ import (
 	"time"
 
 	"github.com/nerdynz/dat/common"
+	"github.com/nerdynz/dat/postgres"
 )
 
-// Dialect is the active SQLDialect.
-var Dialect SQLDialect
+// Dialect is the dialect of builders which have none. Builders created by a
+// runner have the dialect of their database, use WithDialect to write SQL for
+// another database with the builders of this package. Defaults to Postgres.
+var Dialect SQLDialect = postgres.New()
 
 // SQLDialect represents a vendor specific SQL dialect.
 type SQLDialect interface {
@@ -17,4 +20,27 @@ type SQLDialect interface {
 	WriteIdentifier(buf common.BufferWriter, column string)
 	// WriteFormattedTime writes a time formatted for the database
 	WriteFormattedTime(buf common.BufferWriter, t time.Time)
+	// WriteBool writes a boolean literal.
+	WriteBool(buf common.BufferWriter, b bool)
+	// Rebind rewrites the $1, $2 ... placeholders of SQL built by dat into
+	// the placeholders of the database, reordering args if needed.
+	Rebind(sql string, args []interface{}) (string, []interface{}, error)
+	// WriteLimitOffset writes the LIMIT and OFFSET clauses of a statement.
+	WriteLimitOffset(buf common.BufferWriter, limit uint64, hasLimit bool, offset uint64, hasOffset bool)
+	// Supports returns true if the database supports feature.
+	Supports(feature common.Feature) bool
+}
+
+// DialectSetter is implemented by builders, which write their SQL in the
+// dialect set. Runners set the dialect of their database.
+type DialectSetter interface {
+	SetDialect(dialect SQLDialect)
+}
+
+// dialectOrDefault returns dialect, or Dialect if dialect is nil.
+func dialectOrDefault(dialect SQLDialect) SQLDialect {
+	if dialect != nil {
+		return dialect
+	}
+	return Dialect
 }
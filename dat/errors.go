@@ -18,6 +18,9 @@ var (
 	ErrInvalidOperation = NewError("invalid operation")
 	// ErrDisconnectedExecer is returned when a dat builder is used directly instead of through sqlx-runner
 	ErrDisconnectedExecer = NewError("dat builders are disconnected, use sqlx-runner package")
+	// ErrInvalidIdentifier occurs when a column is not an identifier, see
+	// the WithStrictIdentifiers method of builders.
+	ErrInvalidIdentifier = NewError("invalid identifier")
 )
 
 // Error are errors returned by Dat.
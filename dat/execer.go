@@ -13,6 +13,7 @@ type Result struct {
 type Execer interface {
 	Cache(id string, ttl time.Duration, invalidate bool) Execer
 	Timeout(time.Duration) Execer
+	OnPrimary() Execer
 	Interpolate() (string, []interface{}, error)
 	Exec() (*Result, error)
 
@@ -22,6 +23,8 @@ type Execer interface {
 	QueryStructs(dest interface{}) error
 	QueryObject(dest interface{}) error
 	QueryJSON() ([]byte, error)
+
+	Explain(opts ExplainOptions) (*QueryPlan, error)
 }
 
 var nullExecer = &disconnectedExecer{}
@@ -38,6 +41,10 @@ func (nop *disconnectedExecer) Timeout(time.Duration) Execer {
 	return nil
 }
 
+func (nop *disconnectedExecer) OnPrimary() Execer {
+	return nop
+}
+
 // Exec panics when Exec is called.
 func (nop *disconnectedExecer) Exec() (*Result, error) {
 	return nil, ErrDisconnectedExecer
@@ -76,3 +83,8 @@ func (nop *disconnectedExecer) QueryObject(dest interface{}) error {
 func (nop *disconnectedExecer) QueryJSON() ([]byte, error) {
 	return nil, ErrDisconnectedExecer
 }
+
+// Explain returns ErrDisconnectedExecer.
+func (nop *disconnectedExecer) Explain(opts ExplainOptions) (*QueryPlan, error) {
+	return nil, ErrDisconnectedExecer
+}
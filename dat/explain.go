@@ -0,0 +1,99 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ExplainOptions are the options of Explain.
+type ExplainOptions struct {
+	// Analyze executes the statement to report actual times and rows.
+	// Statements other than SELECT are executed in a transaction which is
+	// rolled back.
+	Analyze bool
+
+	// Buffers reports the buffer usage. It requires Analyze before
+	// Postgres 13.
+	Buffers bool
+}
+
+// ExplainSQL wraps sql in EXPLAIN (FORMAT JSON) with the options.
+func (opts ExplainOptions) ExplainSQL(sql string) string {
+	var buf bytes.Buffer
+	buf.WriteString("EXPLAIN (FORMAT JSON")
+	if opts.Analyze {
+		buf.WriteString(", ANALYZE")
+	}
+	if opts.Buffers {
+		buf.WriteString(", BUFFERS")
+	}
+	buf.WriteString(") ")
+	buf.WriteString(sql)
+	return buf.String()
+}
+
+// QueryPlan is the output of EXPLAIN (FORMAT JSON). Times are in
+// milliseconds and only set when analyzed.
+type QueryPlan struct {
+	Plan          *Plan   `json:"Plan"`
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+// Plan is a node of a query plan.
+type Plan struct {
+	NodeType     string `json:"Node Type"`
+	RelationName string `json:"Relation Name,omitempty"`
+	Alias        string `json:"Alias,omitempty"`
+	IndexName    string `json:"Index Name,omitempty"`
+	Filter       string `json:"Filter,omitempty"`
+	IndexCond    string `json:"Index Cond,omitempty"`
+
+	StartupCost float64 `json:"Startup Cost"`
+	TotalCost   float64 `json:"Total Cost"`
+	PlanRows    float64 `json:"Plan Rows"`
+	PlanWidth   int     `json:"Plan Width"`
+
+	ActualStartupTime float64 `json:"Actual Startup Time,omitempty"`
+	ActualTotalTime   float64 `json:"Actual Total Time,omitempty"`
+	ActualRows        float64 `json:"Actual Rows,omitempty"`
+	ActualLoops       float64 `json:"Actual Loops,omitempty"`
+
+	SharedHitBlocks  int64 `json:"Shared Hit Blocks,omitempty"`
+	SharedReadBlocks int64 `json:"Shared Read Blocks,omitempty"`
+
+	Plans []*Plan `json:"Plans,omitempty"`
+
+	// Properties has all the properties of the node, including those
+	// without a field.
+	Properties map[string]interface{} `json:"-"`
+}
+
+// UnmarshalJSON decodes a plan node keeping all its properties.
+func (p *Plan) UnmarshalJSON(b []byte) error {
+	type plan Plan
+	if err := json.Unmarshal(b, (*plan)(p)); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &p.Properties)
+}
+
+// Walk calls fn for this node and its descendants, depth first.
+func (p *Plan) Walk(fn func(node *Plan)) {
+	fn(p)
+	for _, child := range p.Plans {
+		child.Walk(fn)
+	}
+}
+
+// ParseQueryPlan parses the output of EXPLAIN (FORMAT JSON).
+func ParseQueryPlan(b []byte) (*QueryPlan, error) {
+	var plans []*QueryPlan
+	if err := json.Unmarshal(b, &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, NewError("EXPLAIN returned no plan")
+	}
+	return plans[0], nil
+}
@@ -0,0 +1,62 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainSQL(t *testing.T) {
+	assert.Equal(t, "EXPLAIN (FORMAT JSON) SELECT 1", ExplainOptions{}.ExplainSQL("SELECT 1"))
+	assert.Equal(t, "EXPLAIN (FORMAT JSON, ANALYZE, BUFFERS) SELECT 1", ExplainOptions{Analyze: true, Buffers: true}.ExplainSQL("SELECT 1"))
+}
+
+func TestParseQueryPlan(t *testing.T) {
+	out := `[
+	  {
+	    "Plan": {
+	      "Node Type": "Hash Join",
+	      "Startup Cost": 1.1,
+	      "Total Cost": 20.5,
+	      "Plan Rows": 10,
+	      "Plan Width": 36,
+	      "Actual Total Time": 0.5,
+	      "Actual Rows": 6,
+	      "Actual Loops": 1,
+	      "Hash Cond": "(posts.user_id = people.id)",
+	      "Plans": [
+	        {"Node Type": "Seq Scan", "Relation Name": "posts", "Alias": "posts", "Total Cost": 10},
+	        {"Node Type": "Index Scan", "Relation Name": "people", "Index Name": "people_pkey", "Total Cost": 8}
+	      ]
+	    },
+	    "Planning Time": 0.2,
+	    "Execution Time": 0.7
+	  }
+	]`
+
+	qp, err := ParseQueryPlan([]byte(out))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.2, qp.PlanningTime)
+	assert.Equal(t, 0.7, qp.ExecutionTime)
+	assert.Equal(t, "Hash Join", qp.Plan.NodeType)
+	assert.Equal(t, 20.5, qp.Plan.TotalCost)
+	assert.Equal(t, float64(6), qp.Plan.ActualRows)
+	assert.Equal(t, "(posts.user_id = people.id)", qp.Plan.Properties["Hash Cond"])
+
+	var relations []string
+	qp.Plan.Walk(func(node *Plan) {
+		if node.RelationName != "" {
+			relations = append(relations, node.RelationName)
+		}
+	})
+	assert.Equal(t, []string{"posts", "people"}, relations)
+	assert.Equal(t, "people_pkey", qp.Plan.Plans[1].IndexName)
+
+	_, err = ParseQueryPlan([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestExplainDisconnected(t *testing.T) {
+	_, err := Select("a").From("b").Explain(ExplainOptions{})
+	assert.Equal(t, ErrDisconnectedExecer, err)
+}
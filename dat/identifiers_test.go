@@ -0,0 +1,77 @@
+package dat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictIdentifiers(t *testing.T) {
+	unsafe := "id = 1 OR 1=1 --"
+
+	_, _, err := Select("id").From("people").Where(Eq{unsafe: 1}).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = InsertInto("people").Whitelist("name", unsafe).Values("a", "b").WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = InsertInto("people").Columns("name").Values("a").Returning(unsafe).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Update("people").Set(unsafe, 1).Where("id = $1", 1).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Upsert("people").Columns("name").Values("a").Where("name = $1", "a").Returning(unsafe).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	// aliases are only allowed for tables
+	_, _, err = Select("id").From("people").Where(Eq{"id foo": 1}).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Update("people").Set("name AS n", 1).Where("id = $1", 1).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = InsertInto("people").Columns("name n").Values("a").WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = InsertInto("people").Columns("name").Values("a").Returning("id i").WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Upsert("people p").Columns("name").Values("a").Where("name = $1", "a").WithStrictIdentifiers().ToSQL()
+	assert.NoError(t, err)
+
+	sql, _, err := Select("id").From("people").Where(Eq{"people.user": 1}).WithStrictIdentifiers().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM people WHERE (people."user" = $1)`, sql)
+
+	sql, _, err = InsertInto("people").Columns("name").Values("a").Returning("*").WithStrictIdentifiers().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO people (name) VALUES ($1) RETURNING *", sql)
+
+	table := "t; DROP TABLE x"
+
+	_, _, err = InsertInto(table).Columns("name").Values("a").WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Update(table).Set("name", "a").WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = DeleteFrom(table).Where("id = $1", 1).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	_, _, err = Select("id").From(table).WithStrictIdentifiers().ToSQL()
+	assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+
+	sql, _, err = Select("id").From("public.user u").WithStrictIdentifiers().ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM public."user" u`, sql)
+
+	// identifiers are only checked by strict builders
+	sql, _, err = Select("id").From("people p JOIN groups g ON g.id = p.group_id").ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM people p JOIN groups g ON g.id = p.group_id", sql)
+
+	_, _, err = Select("id").From("people").Where(Eq{unsafe: 1}).ToSQL()
+	assert.NoError(t, err)
+}
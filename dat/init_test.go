@@ -27,7 +27,7 @@ func quoteSQL(sqlFmt string, cols ...string) string {
 
 func quoteColumn(column string) string {
 	var buffer bytes.Buffer
-	writeIdentifier(&buffer, column)
+	Dialect.WriteIdentifier(&buffer, column)
 	return buffer.String()
 }
 
@@ -3,6 +3,7 @@ package dat
 import (
 	"reflect"
 
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/internal/log"
 )
 
@@ -24,6 +25,8 @@ import (
 //		Returning("id", "name", "email")
 type InsectBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	cols           []string
 	err            error
@@ -83,9 +86,10 @@ func (b *InsectBuilder) Returning(columns ...string) *InsectBuilder {
 	return b
 }
 
-// ToSQL serialized the InsectBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serialized the InsectBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *InsectBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
@@ -108,6 +112,9 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	if b.record == nil && b.isBlacklist {
 		return NewDatSQLError(`Blacklist can only be used in conjunction with Record`)
 	}
+	if !dialect.Supports(common.FeatureWritableCTE) {
+		return NewDatSQLError("Insect is not supported by the dialect")
+	}
 
 	cols := b.cols
 	vals := b.vals
@@ -122,6 +129,15 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	if b.record != nil && cols[0] == "*" {
 		cols = reflectColumns(b.record)
 	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return NewDatSQLErr(err)
+	}
+	if err := checkColumns(b.strictIdentifiers, cols...); err != nil {
+		return NewDatSQLErr(err)
+	}
+	if err := checkColumns(b.strictIdentifiers, returnings...); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	whereAdded := false
 
@@ -174,9 +190,11 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	buf.WriteString("WITH sel AS (")
 
 	sb := NewSelectBuilder(returnings...).
-		From(b.table)
+		From(b.table).
+		WithDialect(dialect)
 	sb.whereFragments = whereFragments
-	selectSQL, args, err = sb.ToSQL()
+	sb.strictIdentifiers = b.strictIdentifiers
+	selectSQL, args, err = sb.toSQL()
 	if err != nil {
 		return NewDatSQLErr(err)
 	}
@@ -185,9 +203,9 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	buf.WriteString("), ins AS (")
 
 	buf.WriteString(" INSERT INTO ")
-	writeIdentifier(buf, b.table)
+	dialect.WriteIdentifier(buf, b.table)
 	buf.WriteString("(")
-	writeIdentifiers(buf, cols, ",")
+	writeIdentifiers(buf, dialect, cols, ",")
 	buf.WriteString(") SELECT ")
 
 	if whereAdded {
@@ -198,7 +216,7 @@ func (b *InsectBuilder) ToSQL() (string, []interface{}, error) {
 	}
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM sel) RETURNING ")
-	writeIdentifiers(buf, returnings, ",")
+	writeIdentifiers(buf, dialect, returnings, ",")
 
 	buf.WriteString(") SELECT * FROM ins UNION ALL SELECT * FROM sel")
 
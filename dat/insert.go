@@ -5,12 +5,15 @@ import (
 	"errors"
 	"reflect"
 
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/internal/log"
 )
 
 // InsertBuilder contains the clauses for an INSERT statement
 type InsertBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	isInterpolated bool
 	table          string
@@ -19,6 +22,7 @@ type InsertBuilder struct {
 	vals           [][]interface{}
 	records        []interface{}
 	returnings     []string
+	secretColumns  func(column string) bool
 	err            error
 }
 
@@ -88,9 +92,16 @@ func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
 	return b
 }
 
-// ToSQL serialized the InsertBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
+// SetSecretColumns masks the values of the columns for which match returns
+// true in logs and errors. See Secret.
+func (b *InsertBuilder) SetSecretColumns(match func(column string) bool) {
+	b.secretColumns = match
+}
+
+// toSQL serialized the InsertBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *InsertBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return "", nil, b.err
 	}
@@ -115,6 +126,10 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 		return "", nil, NewError("Blacklist can only be used in conjunction with Record")
 	}
 
+	if len(b.returnings) > 0 && !dialect.Supports(common.FeatureReturning) {
+		return "", nil, NewError("RETURNING is not supported by the dialect")
+	}
+
 	cols := b.cols
 
 	// reflect fields removing blacklisted columns
@@ -125,19 +140,28 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 	if lenRecords > 0 && cols[0] == "*" {
 		cols = reflectColumns(b.records[0])
 	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return "", nil, err
+	}
+	if err := checkColumns(b.strictIdentifiers, cols...); err != nil {
+		return "", nil, err
+	}
+	if err := checkColumns(b.strictIdentifiers, b.returnings...); err != nil {
+		return "", nil, err
+	}
 
 	var sql bytes.Buffer
 	var args []interface{}
 
 	sql.WriteString("INSERT INTO ")
-	sql.WriteString(b.table)
+	dialect.WriteIdentifier(&sql, b.table)
 	sql.WriteString(" (")
 
 	for i, c := range cols {
 		if i > 0 {
 			sql.WriteRune(',')
 		}
-		writeIdentifier(&sql, c)
+		dialect.WriteIdentifier(&sql, c)
 	}
 	sql.WriteString(") VALUES ")
 
@@ -149,7 +173,10 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 		}
 		buildPlaceholders(&sql, start, len(row))
 
-		for _, v := range row {
+		for j, v := range row {
+			if j < len(cols) {
+				v = secretIf(b.secretColumns, cols[j], v)
+			}
 			args = append(args, v)
 			start++
 		}
@@ -168,8 +195,8 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 			return "", nil, err
 		}
 		buildPlaceholders(&sql, start, len(vals))
-		for _, v := range vals {
-			args = append(args, v)
+		for j, v := range vals {
+			args = append(args, secretIf(b.secretColumns, cols[j], v))
 			start++
 		}
 	}
@@ -181,7 +208,7 @@ func (b *InsertBuilder) ToSQL() (string, []interface{}, error) {
 		} else {
 			sql.WriteRune(',')
 		}
-		writeIdentifier(&sql, c)
+		dialect.WriteIdentifier(&sql, c)
 	}
 
 	return sql.String(), args, nil
@@ -45,16 +45,25 @@ var typeOfTime = reflect.TypeOf(time.Time{})
 
 // Interpolate takes a SQL string with placeholders and a list of arguments to
 // replace them with. Returns a blank string and error if the number of placeholders
-// does not match the number of arguments.
+// does not match the number of arguments. Literals are written in Dialect.
 func Interpolate(sql string, vals []interface{}) (string, []interface{}, error) {
+	return InterpolateWith(Dialect, sql, vals)
+}
+
+// InterpolateWith is Interpolate writing literals in dialect.
+func InterpolateWith(dialect SQLDialect, sql string, vals []interface{}) (string, []interface{}, error) {
 	// Get the number of arguments to add to this query
 	lenVals := len(vals)
 
 	// if there are any []byte types, just pass it through to save memory allocations
 	for i := 0; i < lenVals; i++ {
-		if _, ok := vals[i].([]byte); ok {
+		v := vals[i]
+		if s, ok := v.(SecretValue); ok {
+			v = s.v
+		}
+		if _, ok := v.([]byte); ok {
 			return sql, vals, nil
-		} else if _, ok := vals[i].(*[]byte); ok {
+		} else if _, ok := v.(*[]byte); ok {
 			return sql, vals, nil
 		}
 	}
@@ -102,7 +111,14 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			return ErrArgumentMismatch
 		}
 
-		v := vals[pos]
+		// secret values passed through as arguments stay wrapped so they
+		// are still masked in logs
+		arg := vals[pos]
+		v := arg
+		s, secret := arg.(SecretValue)
+		if secret {
+			v = s.v
+		}
 
 		// mark any arguments not handled with a new placeholder
 		// and the arg to the new arguments slice
@@ -122,7 +138,18 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 				return nil
 			}
 
-			passthroughArg(v)
+			passthroughArg(arg)
+			return nil
+		} else if exp, ok := v.(*Expression); ok && exp != nil {
+			s, args, err := InterpolateWith(dialect, exp.Sql, exp.Args)
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(s)
+			if len(args) > 0 {
+				passthroughArg(args...)
+			}
 			return nil
 		} else if valuer, ok := v.(Expressioner); ok {
 			valueOfV := reflect.ValueOf(v)
@@ -152,7 +179,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			if err != nil {
 				return err
 			}
-			Dialect.WriteStringLiteral(buf, s)
+			dialect.WriteStringLiteral(buf, s)
 			return nil
 		} else if valuer, ok := v.(driver.Valuer); ok {
 			val, err := valuer.Value()
@@ -185,7 +212,7 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			if !utf8.ValidString(str) {
 				return ErrNotUTF8
 			}
-			Dialect.WriteStringLiteral(buf, str)
+			dialect.WriteStringLiteral(buf, str)
 		} else if isInt(kindOfV) {
 			var ival = valueOfV.Int()
 			writeInt64(buf, ival)
@@ -196,16 +223,11 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 			var fval = valueOfV.Float()
 			buf.WriteString(strconv.FormatFloat(fval, 'f', -1, 64))
 		} else if kindOfV == reflect.Bool {
-			var bval = valueOfV.Bool()
-			if bval {
-				buf.WriteString(`'t'`)
-			} else {
-				buf.WriteString(`'f'`)
-			}
+			dialect.WriteBool(buf, valueOfV.Bool())
 		} else if kindOfV == reflect.Struct {
 			if typeOfV := valueOfV.Type(); typeOfV == typeOfTime {
 				t := valueOfV.Interface().(time.Time)
-				Dialect.WriteFormattedTime(buf, t)
+				dialect.WriteFormattedTime(buf, t)
 			} else {
 				return ErrInvalidValue
 			}
@@ -245,12 +267,14 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 					if !utf8.ValidString(str) {
 						return ErrNotUTF8
 					}
-					Dialect.WriteStringLiteral(buf, str)
+					dialect.WriteStringLiteral(buf, str)
 				}
 			} else {
 				return ErrInvalidSliceValue
 			}
 			buf.WriteRune(')')
+		} else if secret {
+			passthroughArg(Secret(v))
 		} else {
 			passthroughArg(v)
 		}
@@ -309,13 +333,40 @@ func Interpolate(sql string, vals []interface{}) (string, []interface{}, error)
 	return buf.String(), newArgs, nil
 }
 
-func interpolate(builder Builder) (string, []interface{}, error) {
-	sql, args, err := builder.ToSQL()
+// positionalBuilder is implemented by the builders of this package, which
+// build SQL with $1, $2 ... placeholders before rebinding it for a dialect.
+type positionalBuilder interface {
+	toSQL() (string, []interface{}, error)
+}
+
+// PositionalSQL builds the SQL of builder with $1, $2 ... placeholders
+// whatever its dialect, as expected by InterpolateWith and SQLDialect.Rebind.
+func PositionalSQL(builder Builder) (string, []interface{}, error) {
+	if pb, ok := builder.(positionalBuilder); ok {
+		return pb.toSQL()
+	}
+	return builder.ToSQL()
+}
+
+func toSQL(builder positionalBuilder, dialect SQLDialect) (string, []interface{}, error) {
+	sql, args, err := builder.toSQL()
+	if err != nil {
+		return "", nil, err
+	}
+	return dialectOrDefault(dialect).Rebind(sql, args)
+}
+
+func interpolate(builder Builder, dialect SQLDialect) (string, []interface{}, error) {
+	dialect = dialectOrDefault(dialect)
+	sql, args, err := PositionalSQL(builder)
 	if err != nil {
 		return "", nil, err
 	}
 	if builder.IsInterpolated() {
-		return Interpolate(sql, args)
+		sql, args, err = InterpolateWith(dialect, sql, args)
+		if err != nil {
+			return "", nil, err
+		}
 	}
-	return sql, args, nil
+	return dialect.Rebind(sql, args)
 }
@@ -54,7 +54,7 @@ func TestIssue46(t *testing.T) {
 
 	sql, args, err = Select("id").From("public.table").ToSQL()
 	assert.NoError(t, err)
-	assert.Equal(t, stripWS(`SELECT id FROM public.table`), stripWS(sql))
+	assert.Equal(t, stripWS(`SELECT id FROM public."table"`), stripWS(sql))
 	assert.Nil(t, args)
 
 	// raw SQL should not escape anything
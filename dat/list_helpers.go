@@ -1,22 +1,46 @@
 package dat
 
 import (
+	"fmt"
+
 	"github.com/nerdynz/dat/common"
 )
 
 var bufPool = common.NewBufferPool()
 
-func writeIdentifiers(buf common.BufferWriter, columns []string, join string) {
+func writeIdentifiers(buf common.BufferWriter, dialect SQLDialect, columns []string, join string) {
 	for i, column := range columns {
 		if i > 0 {
 			buf.WriteString(join)
 		}
-		buf.WriteString(column)
+		dialect.WriteIdentifier(buf, column)
 	}
 }
 
-func writeIdentifier(buf common.BufferWriter, name string) {
-	buf.WriteString(name)
+// checkColumns returns ErrInvalidIdentifier if strict is true and one of
+// columns is not a column such as `column` or `table.column`. Aliases are
+// refused.
+func checkColumns(strict bool, columns ...string) error {
+	return checkIdentifiers(strict, columns, false)
+}
+
+// checkTables returns ErrInvalidIdentifier if strict is true and one of
+// tables is not a table such as `table` or `schema.table alias`.
+func checkTables(strict bool, tables ...string) error {
+	return checkIdentifiers(strict, tables, true)
+}
+
+func checkIdentifiers(strict bool, names []string, allowAlias bool) error {
+	if !strict {
+		return nil
+	}
+	for _, name := range names {
+		id, ok := common.ParseIdentifier(name)
+		if !ok || (id.Alias != nil && !allowAlias) {
+			return fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+		}
+	}
+	return nil
 }
 
 func buildPlaceholders(buf common.BufferWriter, start, length int) {
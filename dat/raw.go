@@ -3,6 +3,7 @@ package dat
 // RawBuilder builds SQL from raw SQL.
 type RawBuilder struct {
 	Execer
+	dialect SQLDialect
 
 	isInterpolated bool
 	sql            string
@@ -14,7 +15,7 @@ func NewRawBuilder(sql string, args ...interface{}) *RawBuilder {
 	return &RawBuilder{sql: sql, args: args, isInterpolated: EnableInterpolation}
 }
 
-// ToSQL implements builder interface
-func (b *RawBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL returns the raw SQL and arguments.
+func (b *RawBuilder) toSQL() (string, []interface{}, error) {
 	return b.sql, b.args, nil
 }
@@ -54,6 +54,10 @@ func (scope *MapScope) mergeClone(fields M) *MapScope {
 
 // ToSQL converts this scope's SQL to SQL and args.
 func (scope *MapScope) ToSQL(table string) (string, []interface{}) {
+	return scope.toSQL(Dialect, table)
+}
+
+func (scope *MapScope) toSQL(dialect SQLDialect, table string) (string, []interface{}) {
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 
@@ -62,7 +66,7 @@ func (scope *MapScope) ToSQL(table string) (string, []interface{}) {
 	sql := reField.ReplaceAllStringFunc(scope.SQL, func(found string) string {
 		buf.Reset()
 		if found == ":TABLE" {
-			writeIdentifier(buf, table)
+			dialect.WriteIdentifier(buf, table)
 			return buf.String()
 		}
 		if args == nil {
@@ -78,14 +82,23 @@ func (scope *MapScope) ToSQL(table string) (string, []interface{}) {
 	return sql, args
 }
 
-// escapeScopeTable escapes :TABLE in sql using Dialect.WriteIdentifer.
-func escapeScopeTable(sql string, table string) string {
+// scopeToSQL converts scope to SQL and args, writing the table of a MapScope
+// in dialect.
+func scopeToSQL(dialect SQLDialect, scope Scope, table string) (string, []interface{}) {
+	if mapScope, ok := scope.(*MapScope); ok {
+		return mapScope.toSQL(dialect, table)
+	}
+	return scope.ToSQL(table)
+}
+
+// escapeScopeTable escapes :TABLE in sql using dialect.WriteIdentifer.
+func escapeScopeTable(dialect SQLDialect, sql string, table string) string {
 	if !strings.Contains(sql, ":TABLE") {
 		return sql
 	}
 
 	var buf bytes.Buffer
-	writeIdentifier(&buf, table)
+	dialect.WriteIdentifier(&buf, table)
 	quoted := buf.String()
 	return strings.Replace(sql, ":TABLE", quoted, -1)
 }
@@ -0,0 +1,76 @@
+package dat
+
+import (
+	"database/sql/driver"
+)
+
+// Redacted replaces the value of a secret in logs and errors.
+const Redacted = "<redacted>"
+
+// SecretValue is an argument which must not be logged. It is sent to the
+// database as is but prints as Redacted. See Secret.
+type SecretValue struct {
+	v interface{}
+}
+
+// Secret wraps v so it is masked in logs, errors and the interpolated SQL
+// which is logged, while the database still receives v.
+//
+//	db.Update("users").Set("password_hash", dat.Secret(hash))
+func Secret(v interface{}) SecretValue {
+	if s, ok := v.(SecretValue); ok {
+		return s
+	}
+	return SecretValue{v: v}
+}
+
+// Unwrap returns the wrapped value.
+func (s SecretValue) Unwrap() interface{} {
+	return s.v
+}
+
+// Value implements driver.Valuer.
+func (s SecretValue) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.v)
+}
+
+// String returns Redacted.
+func (s SecretValue) String() string {
+	return Redacted
+}
+
+// GoString returns Redacted.
+func (s SecretValue) GoString() string {
+	return Redacted
+}
+
+// MarshalJSON encodes Redacted.
+func (s SecretValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+// IsSecret returns true if v was wrapped by Secret.
+func IsSecret(v interface{}) bool {
+	_, ok := v.(SecretValue)
+	return ok
+}
+
+// SecretColumnsSetter is implemented by builders which bind values to
+// columns, InsertBuilder and UpdateBuilder. The values of columns for which
+// match returns true are wrapped by Secret when the SQL is built.
+type SecretColumnsSetter interface {
+	SetSecretColumns(match func(column string) bool)
+}
+
+// secretIf wraps v by Secret if match reports column as secret. Expressions
+// and raw SQL are left as is since they are written into the SQL.
+func secretIf(match func(column string) bool, column string, v interface{}) interface{} {
+	if match == nil || !match(column) {
+		return v
+	}
+	switch v.(type) {
+	case UnsafeString, Expressioner:
+		return v
+	}
+	return Secret(v)
+}
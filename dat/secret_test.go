@@ -0,0 +1,84 @@
+package dat
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("hunter2")
+	assert.Equal(t, Redacted, fmt.Sprintf("%v", s))
+	assert.Equal(t, Redacted, fmt.Sprintf("%#v", s))
+	assert.Equal(t, "hunter2", s.Unwrap())
+	assert.Equal(t, s, Secret(s))
+	assert.True(t, IsSecret(s))
+	assert.False(t, IsSecret("hunter2"))
+
+	v, err := s.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	v, err = Secret(42).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	b, err := json.Marshal([]interface{}{1, s})
+	assert.NoError(t, err)
+	var decoded []interface{}
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, []interface{}{1.0, Redacted}, decoded)
+}
+
+func TestInterpolateSecret(t *testing.T) {
+	sql, args, err := Interpolate("SELECT $1, $2", []interface{}{Secret("hunter2"), Secret([]int{1, 2})})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 'hunter2', (1,2)", sql)
+	assert.Empty(t, args)
+
+	bin := Secret([]byte{1})
+	sql, args, err = Interpolate("SELECT $1", []interface{}{bin})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT $1", sql)
+	assert.Equal(t, []interface{}{bin}, args)
+
+	doc := Secret(JSON(`{"token":"hunter2"}`))
+	sql, args, err = Interpolate("SELECT $1, $2", []interface{}{1, doc})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1, $1", sql)
+	assert.Equal(t, []interface{}{doc}, args)
+}
+
+func TestSecretColumns(t *testing.T) {
+	match := func(column string) bool { return column == "password" }
+
+	ins := InsertInto("users").Columns("email", "password").Values("a@b.c", "hunter2")
+	ins.SetSecretColumns(match)
+	sql, args, err := ins.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (email,password) VALUES ($1,$2)", sql)
+	assert.Equal(t, []interface{}{"a@b.c", Secret("hunter2")}, args)
+
+	rec := struct {
+		Email    string `db:"email"`
+		Password string `db:"password"`
+	}{"a@b.c", "hunter2"}
+	ins = InsertInto("users").Columns("email", "password").Record(&rec)
+	ins.SetSecretColumns(match)
+	_, args, err = ins.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a@b.c", Secret("hunter2")}, args)
+
+	upd := Update("users").Set("password", "hunter2").Set("updated_at", NOW).Where("id = $1", 1)
+	upd.SetSecretColumns(match)
+	sql, args, err = upd.ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{Secret("hunter2"), NOW, 1}, args)
+
+	sql, args, err = Interpolate(sql, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET password = 'hunter2', updated_at = NOW() WHERE (id = 1)", sql)
+	assert.Empty(t, args)
+}
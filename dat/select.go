@@ -5,6 +5,8 @@ import "github.com/nerdynz/dat/internal/log"
 // SelectBuilder contains the clauses for a SELECT statement
 type SelectBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	isDistinct      bool
 	distinctColumns []string
@@ -68,6 +70,11 @@ func (b *SelectBuilder) For(options ...string) *SelectBuilder {
 	return b
 }
 
+// IsLocking returns true if the SELECT locks rows with a FOR clause.
+func (b *SelectBuilder) IsLocking() bool {
+	return len(b.fors) > 0
+}
+
 // ScopeMap uses a predefined scope in place of WHERE.
 func (b *SelectBuilder) ScopeMap(mapScope *MapScope, m M) *SelectBuilder {
 	b.scope = mapScope.mergeClone(m)
@@ -144,9 +151,10 @@ func (b *SelectBuilder) Paginate(page, perPage uint64) *SelectBuilder {
 	return b
 }
 
-// ToSQL serialized the SelectBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serialized the SelectBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *SelectBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
@@ -157,6 +165,9 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 	if len(b.table) == 0 {
 		return NewDatSQLError("no table specified")
 	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -187,13 +198,13 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 	}
 
 	buf.WriteString(" FROM ")
-	buf.WriteString(b.table)
+	dialect.WriteIdentifier(buf, b.table)
 
 	var placeholderStartPos int64 = 1
 	whereFragments := b.whereFragments
 	if b.scope != nil {
 		var where string
-		sql, args2 := b.scope.ToSQL(b.table)
+		sql, args2 := scopeToSQL(dialect, b.scope, b.table)
 		sql, where = splitWhere(sql)
 		buf.WriteString(sql)
 		if where != "" {
@@ -208,7 +219,9 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 
 	if len(whereFragments) > 0 {
 		buf.WriteString(" WHERE ")
-		writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
+		if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, whereFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
 	if len(b.groupBys) > 0 {
@@ -223,23 +236,19 @@ func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
 
 	if len(b.havingFragments) > 0 {
 		buf.WriteString(" HAVING ")
-		writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
+		if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.havingFragments, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
 	if len(b.orderBys) > 0 {
 		buf.WriteString(" ORDER BY ")
-		writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
-	}
-
-	if b.limitValid {
-		buf.WriteString(" LIMIT ")
-		writeUint64(buf, b.limitCount)
+		if err := writeCommaFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.orderBys, &args, &placeholderStartPos); err != nil {
+			return NewDatSQLErr(err)
+		}
 	}
 
-	if b.offsetValid {
-		buf.WriteString(" OFFSET ")
-		writeUint64(buf, b.offsetCount)
-	}
+	dialect.WriteLimitOffset(buf, b.limitCount, b.limitValid, b.offsetCount, b.offsetValid)
 
 	// add FOR clause
 	if len(b.fors) > 0 {
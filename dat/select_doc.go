@@ -1,6 +1,9 @@
 package dat
 
-import "github.com/nerdynz/dat/internal/log"
+import (
+	"github.com/nerdynz/dat/common"
+	"github.com/nerdynz/dat/internal/log"
+)
 
 type subInfo struct {
 	*Expression
@@ -36,14 +39,14 @@ func (b *SelectDocBuilder) Many(column string, sqlOrBuilder interface{}, a ...in
 		b.err = NewError("SelectDocBuilder.Many: sqlOrbuilder accepts only {string, Builder, *SelectDocBuilder} type")
 	case *SelectDocBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := PositionalSQL(t)
 		if err != nil {
 			b.err = err
 			return b
 		}
 		b.subQueries = append(b.subQueries, &subInfo{Expr(sql, args...), column})
 	case Builder:
-		sql, args, err := t.ToSQL()
+		sql, args, err := PositionalSQL(t)
 		if err != nil {
 			b.err = err
 			return b
@@ -62,14 +65,14 @@ func (b *SelectDocBuilder) One(column string, sqlOrBuilder interface{}, a ...int
 		b.err = NewError("sqlOrbuilder accepts only {string, Builder, *SelectDocBuilder} type")
 	case *SelectDocBuilder:
 		t.isParent = false
-		sql, args, err := t.ToSQL()
+		sql, args, err := PositionalSQL(t)
 		if err != nil {
 			b.err = err
 			return b
 		}
 		b.subQueriesOne = append(b.subQueriesOne, &subInfo{Expr(sql, args...), column})
 	case Builder:
-		sql, args, err := t.ToSQL()
+		sql, args, err := PositionalSQL(t)
 		if err != nil {
 			b.err = err
 			return b
@@ -81,9 +84,10 @@ func (b *SelectDocBuilder) One(column string, sqlOrBuilder interface{}, a ...int
 	return b
 }
 
-// ToSQL serialized the SelectBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serialized the SelectBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *SelectDocBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
@@ -94,6 +98,14 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 	if len(b.table) == 0 && b.innerSQL == nil {
 		return NewDatSQLError("no table specified")
 	}
+	if b.innerSQL == nil {
+		if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+			return NewDatSQLErr(err)
+		}
+	}
+	if !dialect.Supports(common.FeatureSelectDoc) {
+		return NewDatSQLError("SelectDoc is not supported by the dialect")
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
@@ -165,7 +177,7 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeIdentifier(buf, sub.alias)
+		dialect.WriteIdentifier(buf, sub.alias)
 	}
 
 	for _, sub := range b.subQueriesOne {
@@ -176,18 +188,18 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 		buf.WriteString(") AS dat__")
 		buf.WriteString(sub.alias)
 		buf.WriteString(") AS ")
-		writeIdentifier(buf, sub.alias)
+		dialect.WriteIdentifier(buf, sub.alias)
 	}
 	whereFragments := b.whereFragments
 	if b.innerSQL != nil {
 		b.innerSQL.WriteRelativeArgs(buf, &args, &placeholderStartPos)
 	} else {
 		buf.WriteString(" FROM ")
-		buf.WriteString(b.table)
+		dialect.WriteIdentifier(buf, b.table)
 
 		if b.scope != nil {
 			var where string
-			sql, args2 := b.scope.ToSQL(b.table)
+			sql, args2 := scopeToSQL(dialect, b.scope, b.table)
 			sql, where = splitWhere(sql)
 			buf.WriteString(sql)
 			if where != "" {
@@ -201,7 +213,9 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 
 		if len(whereFragments) > 0 {
 			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, whereFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, whereFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
 		// if b.scope == nil {
@@ -226,23 +240,19 @@ func (b *SelectDocBuilder) ToSQL() (string, []interface{}, error) {
 
 		if len(b.havingFragments) > 0 {
 			buf.WriteString(" HAVING ")
-			writeAndFragmentsToSQL(buf, b.havingFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.havingFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
 		if len(b.orderBys) > 0 {
 			buf.WriteString(" ORDER BY ")
-			writeCommaFragmentsToSQL(buf, b.orderBys, &args, &placeholderStartPos)
-		}
-
-		if b.limitValid {
-			buf.WriteString(" LIMIT ")
-			writeUint64(buf, b.limitCount)
+			if err := writeCommaFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.orderBys, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 
-		if b.offsetValid {
-			buf.WriteString(" OFFSET ")
-			writeUint64(buf, b.offsetCount)
-		}
+		dialect.WriteLimitOffset(buf, b.limitCount, b.limitValid, b.offsetCount, b.offsetValid)
 
 		// add FOR clause
 		if len(b.fors) > 0 {
@@ -306,7 +316,7 @@ func (b *SelectDocBuilder) ScopeMap(mapScope *MapScope, m M) *SelectDocBuilder {
 // Scope uses a predefined scope in place of WHERE.
 func (b *SelectDocBuilder) Scope(sql string, args ...interface{}) *SelectDocBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
-		return escapeScopeTable(sql, table), args
+		return escapeScopeTable(dialectOrDefault(b.dialect), sql, table), args
 	})
 	return b
 }
@@ -193,7 +193,7 @@ func TestNestedSelecDocWhere(t *testing.T) {
 						FROM users u
 						WHERE (u.id = $1)
 					) AS dat__user
-				) AS user
+				) AS "user"
 			FROM games
 			WHERE (id = $2)
 		) as dat__item
@@ -0,0 +1,155 @@
+package dat
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes classified by the Is* helpers. See
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	SQLStateNotNullViolation     = "23502"
+	SQLStateForeignKeyViolation  = "23503"
+	SQLStateUniqueViolation      = "23505"
+	SQLStateCheckViolation       = "23514"
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+	SQLStateQueryCanceled        = "57014"
+)
+
+// SQLError is an error returned by the database. It wraps the driver's
+// error, which remains reachable through errors.As, and adds the operation
+// which failed.
+type SQLError struct {
+	// Op is the operation which failed, such as "queryStruct".
+	Op string
+	// Code is the SQLSTATE of the error, empty if the driver did not
+	// report one.
+	Code string
+	// Constraint is the name of the violated constraint, if any.
+	Constraint string
+	// Err is the driver's error.
+	Err error
+}
+
+// WrapSQLError wraps a driver error in a SQLError. It returns nil for nil.
+func WrapSQLError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	sqlErr := &SQLError{Op: op, Err: err}
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		sqlErr.Code = string(pe.Code)
+		sqlErr.Constraint = pe.Constraint
+	}
+	return sqlErr
+}
+
+func (e *SQLError) Error() string {
+	if e.Op == "" {
+		return e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the driver's error.
+func (e *SQLError) Unwrap() error {
+	return e.Err
+}
+
+// SQLState returns the SQLSTATE of err, or "" if err is not a database
+// error.
+func SQLState(err error) string {
+	var sqlErr *SQLError
+	if errors.As(err, &sqlErr) && sqlErr.Code != "" {
+		return sqlErr.Code
+	}
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		return string(pe.Code)
+	}
+	return ""
+}
+
+// ConstraintName returns the name of the constraint violated by err, or ""
+// if none.
+func ConstraintName(err error) string {
+	var sqlErr *SQLError
+	if errors.As(err, &sqlErr) && sqlErr.Constraint != "" {
+		return sqlErr.Constraint
+	}
+	var pe *pq.Error
+	if errors.As(err, &pe) {
+		return pe.Constraint
+	}
+	return ""
+}
+
+// IsNoRows returns true if err is sql.ErrNoRows.
+func IsNoRows(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// IsUniqueViolation returns true if err violates a unique constraint.
+func IsUniqueViolation(err error) bool {
+	return SQLState(err) == SQLStateUniqueViolation
+}
+
+// IsForeignKeyViolation returns true if err violates a foreign key
+// constraint.
+func IsForeignKeyViolation(err error) bool {
+	return SQLState(err) == SQLStateForeignKeyViolation
+}
+
+// IsNotNullViolation returns true if err violates a not-null constraint.
+func IsNotNullViolation(err error) bool {
+	return SQLState(err) == SQLStateNotNullViolation
+}
+
+// IsCheckViolation returns true if err violates a check constraint.
+func IsCheckViolation(err error) bool {
+	return SQLState(err) == SQLStateCheckViolation
+}
+
+// IsSerializationFailure returns true if a transaction failed because of
+// concurrent transactions. The transaction may succeed if retried.
+func IsSerializationFailure(err error) bool {
+	return SQLState(err) == SQLStateSerializationFailure
+}
+
+// IsDeadlock returns true if a transaction was aborted to resolve a
+// deadlock. The transaction may succeed if retried.
+func IsDeadlock(err error) bool {
+	return SQLState(err) == SQLStateDeadlockDetected
+}
+
+// QueryError is returned when a statement fails. It carries the context of
+// the statement, so callers can report failures without scraping logs.
+type QueryError struct {
+	// SQL is the statement, interpolated if interpolation is enabled.
+	SQL string
+	// Args are the arguments of the statement with sensitive values
+	// redacted. Nil if the statement was interpolated.
+	Args []interface{}
+	// Builder is the kind of builder which built the statement, such as
+	// "Select", or "Exec" for SQL executed directly.
+	Builder string
+	// Elapsed is the time until the statement failed.
+	Elapsed time.Duration
+	// Err is the cause, usually a *SQLError.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("dat: %s failed after %s: %v", e.Builder, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,74 @@
+package dat
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapSQLError(t *testing.T) {
+	assert.Nil(t, WrapSQLError("exec", nil))
+
+	pe := &pq.Error{Code: "23505", Constraint: "people_email_key", Message: "duplicate key value"}
+	err := WrapSQLError("exec", pe)
+	assert.Equal(t, "exec: pq: duplicate key value", err.Error())
+
+	var sqlErr *SQLError
+	if assert.True(t, errors.As(err, &sqlErr)) {
+		assert.Equal(t, "23505", sqlErr.Code)
+		assert.Equal(t, "people_email_key", sqlErr.Constraint)
+	}
+	var driverErr *pq.Error
+	assert.True(t, errors.As(err, &driverErr))
+	assert.True(t, errors.Is(err, pe))
+}
+
+func TestSQLErrorClassification(t *testing.T) {
+	wrapped := func(code string) error {
+		return fmt.Errorf("context: %w", WrapSQLError("exec", &pq.Error{Code: pq.ErrorCode(code)}))
+	}
+
+	assert.True(t, IsUniqueViolation(wrapped("23505")))
+	assert.False(t, IsUniqueViolation(wrapped("23503")))
+	assert.True(t, IsForeignKeyViolation(wrapped("23503")))
+	assert.True(t, IsNotNullViolation(wrapped("23502")))
+	assert.True(t, IsCheckViolation(wrapped("23514")))
+	assert.True(t, IsSerializationFailure(wrapped("40001")))
+	assert.True(t, IsDeadlock(wrapped("40P01")))
+	assert.True(t, IsSerializationFailure(&pq.Error{Code: "40001"}))
+
+	assert.True(t, IsNoRows(sql.ErrNoRows))
+	assert.True(t, IsNoRows(WrapSQLError("queryStruct", sql.ErrNoRows)))
+	assert.False(t, IsNoRows(errors.New("no rows")))
+
+	assert.Equal(t, "", SQLState(errors.New("other")))
+	assert.Equal(t, "", SQLState(nil))
+	assert.Equal(t, "fk", ConstraintName(&pq.Error{Code: "23503", Constraint: "fk"}))
+	assert.Equal(t, "", ConstraintName(errors.New("other")))
+}
+
+func TestQueryError(t *testing.T) {
+	pe := &pq.Error{Code: "23505", Constraint: "people_pkey", Message: "duplicate key value"}
+	err := error(&QueryError{
+		SQL:     "INSERT INTO people (id) VALUES ($1)",
+		Args:    []interface{}{1},
+		Builder: "Insert",
+		Elapsed: 2 * time.Millisecond,
+		Err:     WrapSQLError("exec", pe),
+	})
+	assert.Equal(t, "dat: Insert failed after 2ms: exec: pq: duplicate key value", err.Error())
+
+	var qe *QueryError
+	if assert.True(t, errors.As(err, &qe)) {
+		assert.Equal(t, "INSERT INTO people (id) VALUES ($1)", qe.SQL)
+		assert.Equal(t, []interface{}{1}, qe.Args)
+	}
+	assert.True(t, IsUniqueViolation(err))
+	assert.Equal(t, "people_pkey", ConstraintName(err))
+	assert.True(t, errors.Is(err, pe))
+}
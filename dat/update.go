@@ -4,12 +4,15 @@ import (
 	"reflect"
 	"strconv"
 
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/internal/log"
 )
 
 // UpdateBuilder contains the clauses for an UPDATE statement
 type UpdateBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	isInterpolated bool
 	table          string
@@ -22,6 +25,7 @@ type UpdateBuilder struct {
 	offsetValid    bool
 	returnings     []string
 	scope          Scope
+	secretColumns  func(column string) bool
 	err            error
 }
 
@@ -108,7 +112,7 @@ func (b *UpdateBuilder) ScopeMap(mapScope *MapScope, m M) *UpdateBuilder {
 // Scope uses a predefined scope in place of WHERE.
 func (b *UpdateBuilder) Scope(sql string, args ...interface{}) *UpdateBuilder {
 	b.scope = ScopeFunc(func(table string) (string, []interface{}) {
-		return escapeScopeTable(sql, table), args
+		return escapeScopeTable(dialectOrDefault(b.dialect), sql, table), args
 	})
 	return b
 }
@@ -150,9 +154,16 @@ func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
 	return b
 }
 
-// ToSQL serialized the UpdateBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
+// SetSecretColumns masks the values of the columns for which match returns
+// true in logs and errors. See Secret.
+func (b *UpdateBuilder) SetSecretColumns(match func(column string) bool) {
+	b.secretColumns = match
+}
+
+// toSQL serialized the UpdateBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *UpdateBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return "", nil, b.err
 	}
@@ -162,13 +173,30 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 	if len(b.setClauses) == 0 {
 		return "", nil, NewError("no set clauses specified")
 	}
+	if len(b.returnings) > 0 && !dialect.Supports(common.FeatureReturning) {
+		return "", nil, NewError("RETURNING is not supported by the dialect")
+	}
+	if b.offsetValid && !dialect.Supports(common.FeatureUpdateOffset) {
+		return "", nil, NewError("OFFSET in UPDATE is not supported by the dialect")
+	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return "", nil, err
+	}
+	for _, c := range b.setClauses {
+		if err := checkColumns(b.strictIdentifiers, c.column); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := checkColumns(b.strictIdentifiers, b.returnings...); err != nil {
+		return "", nil, err
+	}
 
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 	var args []interface{}
 
 	buf.WriteString("UPDATE ")
-	buf.WriteString(b.table)
+	dialect.WriteIdentifier(buf, b.table)
 	buf.WriteString(" SET ")
 
 	var placeholderStartPos int64 = 1
@@ -178,7 +206,7 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
-		writeIdentifier(buf, c.column)
+		dialect.WriteIdentifier(buf, c.column)
 		if e, ok := c.value.(*Expression); ok {
 			start := placeholderStartPos
 			buf.WriteString(" = ")
@@ -199,17 +227,19 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 				}
 			}
 			placeholderStartPos++
-			args = append(args, c.value)
+			args = append(args, secretIf(b.secretColumns, c.column, c.value))
 		}
 	}
 
 	if b.scope == nil {
 		if len(b.whereFragments) > 0 {
 			buf.WriteString(" WHERE ")
-			writeAndFragmentsToSQL(buf, b.whereFragments, &args, &placeholderStartPos)
+			if err := writeAndFragmentsToSQL(buf, dialect, b.strictIdentifiers, b.whereFragments, &args, &placeholderStartPos); err != nil {
+				return NewDatSQLErr(err)
+			}
 		}
 	} else {
-		fragment, err := newWhereFragment(b.scope.ToSQL(b.table))
+		fragment, err := newWhereFragment(scopeToSQL(dialect, b.scope, b.table))
 		if err != nil {
 			return NewDatSQLErr(err)
 		}
@@ -227,15 +257,7 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 		}
 	}
 
-	if b.limitValid {
-		buf.WriteString(" LIMIT ")
-		writeUint64(buf, b.limitCount)
-	}
-
-	if b.offsetValid {
-		buf.WriteString(" OFFSET ")
-		writeUint64(buf, b.offsetCount)
-	}
+	dialect.WriteLimitOffset(buf, b.limitCount, b.limitValid, b.offsetCount, b.offsetValid)
 
 	// Go thru the returning clauses
 	for i, c := range b.returnings {
@@ -244,7 +266,7 @@ func (b *UpdateBuilder) ToSQL() (string, []interface{}, error) {
 		} else {
 			buf.WriteRune(',')
 		}
-		writeIdentifier(buf, c)
+		dialect.WriteIdentifier(buf, c)
 	}
 
 	return buf.String(), args, nil
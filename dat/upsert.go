@@ -3,12 +3,15 @@ package dat
 import (
 	"reflect"
 
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/internal/log"
 )
 
 // UpsertBuilder contains the clauses for an INSERT statement
 type UpsertBuilder struct {
 	Execer
+	dialect           SQLDialect
+	strictIdentifiers bool
 
 	cols           []string
 	err            error
@@ -68,9 +71,10 @@ func (b *UpsertBuilder) Returning(columns ...string) *UpsertBuilder {
 	return b
 }
 
-// ToSQL serialized the UpsertBuilder to a SQL string
-// It returns the string with placeholders and a slice of query arguments
-func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
+// toSQL serialized the UpsertBuilder to a SQL string
+// It returns the string with $1, $2 ... placeholders and a slice of query arguments
+func (b *UpsertBuilder) toSQL() (string, []interface{}, error) {
+	dialect := dialectOrDefault(b.dialect)
 	if b.err != nil {
 		return NewDatSQLErr(b.err)
 	}
@@ -91,8 +95,11 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 	if b.record == nil && b.isBlacklist {
 		return NewDatSQLError(`Blacklist can only be used in conjunction with Record`)
 	}
+	if !dialect.Supports(common.FeatureWritableCTE) && !dialect.Supports(common.FeatureOnDuplicateKeyUpdate) {
+		return NewDatSQLError("Upsert is not supported by the dialect")
+	}
 	// build where clause from columns and values
-	if len(b.whereFragments) == 0 {
+	if len(b.whereFragments) == 0 && dialect.Supports(common.FeatureWritableCTE) {
 		return NewDatSQLError("where clause required for upsert")
 	}
 	cols := b.cols
@@ -107,6 +114,15 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 	if b.record != nil && cols[0] == "*" {
 		cols = reflectColumns(b.record)
 	}
+	if err := checkTables(b.strictIdentifiers, b.table); err != nil {
+		return NewDatSQLErr(err)
+	}
+	if err := checkColumns(b.strictIdentifiers, cols...); err != nil {
+		return NewDatSQLErr(err)
+	}
+	if err := checkColumns(b.strictIdentifiers, returnings...); err != nil {
+		return NewDatSQLErr(err)
+	}
 
 	if len(returnings) == 0 {
 		returnings = cols
@@ -157,6 +173,10 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 		}
 	}
 
+	if !dialect.Supports(common.FeatureWritableCTE) {
+		return b.onDuplicateKeyUpdateToSQL(dialect, cols, vals)
+	}
+
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 
@@ -186,13 +206,14 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 
 	buf.WriteString("WITH upd AS ( ")
 
-	ub := NewUpdateBuilder(b.table)
+	ub := NewUpdateBuilder(b.table).WithDialect(dialect)
 	for i, col := range cols {
 		ub.Set(col, vals[i])
 	}
 	ub.whereFragments = b.whereFragments
+	ub.strictIdentifiers = b.strictIdentifiers
 	ub.returnings = returnings
-	updateSQL, args, err := ub.ToSQL()
+	updateSQL, args, err := ub.toSQL()
 	if err != nil {
 		return NewDatSQLErr(err)
 	}
@@ -201,15 +222,15 @@ func (b *UpsertBuilder) ToSQL() (string, []interface{}, error) {
 	buf.WriteString("), ins AS (")
 
 	buf.WriteString(" INSERT INTO ")
-	writeIdentifier(buf, b.table)
+	dialect.WriteIdentifier(buf, b.table)
 	buf.WriteString("(")
-	writeIdentifiers(buf, cols, ",")
+	writeIdentifiers(buf, dialect, cols, ",")
 	buf.WriteString(") SELECT ")
 
 	writePlaceholders(buf, len(vals), ",", 1)
 
 	buf.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM upd) RETURNING ")
-	writeIdentifiers(buf, returnings, ",")
+	writeIdentifiers(buf, dialect, returnings, ",")
 
 	buf.WriteString(") SELECT * FROM ins UNION ALL SELECT * FROM upd")
 
@@ -227,3 +248,46 @@ func (b *UpsertBuilder) Where(whereSQLOrMap interface{}, args ...interface{}) *U
 	}
 	return b
 }
+
+// onDuplicateKeyUpdateToSQL builds the upsert as an INSERT updating the row
+// on a duplicate key. The row is matched by its primary or unique keys, so
+// Where is refused.
+//
+//	INSERT INTO people (name, email) VALUES ($1, $2)
+//	ON DUPLICATE KEY UPDATE name = VALUES(name), email = VALUES(email)
+func (b *UpsertBuilder) onDuplicateKeyUpdateToSQL(dialect SQLDialect, cols []string, vals []interface{}) (string, []interface{}, error) {
+	if len(b.returnings) > 0 && !dialect.Supports(common.FeatureReturning) {
+		return NewDatSQLError("RETURNING is not supported by the dialect")
+	}
+	if len(b.whereFragments) > 0 {
+		return NewDatSQLError("Where is not supported by ON DUPLICATE KEY UPDATE, rows are matched by their unique keys")
+	}
+
+	buf := bufPool.Get()
+	defer bufPool.Put(buf)
+
+	buf.WriteString("INSERT INTO ")
+	dialect.WriteIdentifier(buf, b.table)
+	buf.WriteString(" (")
+	for i, col := range cols {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		dialect.WriteIdentifier(buf, col)
+	}
+	buf.WriteString(") VALUES ")
+	buildPlaceholders(buf, 1, len(vals))
+
+	buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, col := range cols {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		dialect.WriteIdentifier(buf, col)
+		buf.WriteString(" = VALUES(")
+		dialect.WriteIdentifier(buf, col)
+		buf.WriteRune(')')
+	}
+
+	return buf.String(), vals, nil
+}
@@ -3,6 +3,7 @@ package dat
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -275,21 +276,76 @@ func SQLSliceFromFile(filename string) ([]string, error) {
 	return SQLSliceFromString(string(text))
 }
 
-// ParseDir reads files in a directory "sproc_name"=>"sproc_body"
-func ParseDir(dir string, version string) error {
+// ParseFromReader parses SQL sections marked with --@key=Name and
+// --@sproc=Name, adding the statements of key sections to keys and the
+// bodies of sproc sections to sprocs. A --@sproc marker without a name takes
+// the name from the CREATE FUNCTION statement.
+func ParseFromReader(r io.Reader, keys map[string]string, sprocs map[string]string) error {
+	sections, err := PartitionKV(r, "--@", "=")
+	if err != nil {
+		return err
+	}
+
+	for _, section := range sections {
+		kind := section["_kind"]
+		body := section["_body"]
+		name := section[kind]
+		switch kind {
+		case "key":
+			if name == "" {
+				return fmt.Errorf("--@key requires a name")
+			}
+			if _, ok := keys[name]; ok {
+				return fmt.Errorf("duplicate key %q", name)
+			}
+			keys[name] = body
+		case "sproc":
+			if name == "" {
+				name = ParseSprocName(body)
+			}
+			if name == "" {
+				return fmt.Errorf("could not parse function name of --@sproc")
+			}
+			if _, ok := sprocs[name]; ok {
+				return fmt.Errorf("duplicate sproc %q", name)
+			}
+			sprocs[name] = body
+		}
+	}
+	return nil
+}
+
+// ParseDir parses the .sql files in dir and its subdirectories, see
+// ParseFromReader. It returns the "key_name"=>"statement" and
+// "sproc_name"=>"sproc_body" maps.
+func ParseDir(dir string) (keys map[string]string, sprocs map[string]string, err error) {
+	keys = map[string]string{}
+	sprocs = map[string]string{}
 	walkFn := func(path string, fi os.FileInfo, err error) error {
-		if fi.IsDir() {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(path) != ".sql" {
 			return nil
 		}
-		log.Debug("MustRegisterFunctionsInDir", "dir", dir, "path", path)
+		log.Debug("ParseDir", "dir", dir, "path", path)
 
-		// bytes, err := ioutil.ReadFile(path) // path is the path to the file.
-		// if err != nil {
-		// 	fmt.Println("Fail")
-		// }
-		// reSprocName.FindAllSubmatchIndex
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		err = ParseFromReader(f, keys, sprocs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
 		return nil
 	}
 
-	return filepath.Walk(dir, walkFn)
+	err = filepath.Walk(dir, walkFn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, sprocs, nil
 }
@@ -2,6 +2,9 @@ package dat
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -75,3 +78,43 @@ end; $$ language plpgsql;
 	assert.Equal(t, "create function f_foo() as $$\nbegin\nend; $$ language plpgsql;\n", a[2]["_body"])
 	assert.Equal(t, "", a[2]["sproc"])
 }
+
+func TestParseDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dat-parsedir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "users.sql"), []byte(`
+--@key=selectUsers
+SELECT * FROM users;
+
+--@sproc=f_users
+create function f_users() returns void as $$
+begin
+end; $$ language plpgsql;
+`), 0644)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "sub", "posts.sql"), []byte(`
+--@sproc
+create function f_posts() returns void as $$
+begin
+end; $$ language plpgsql;
+`), 0644)
+	assert.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("--@sproc=ignored\n"), 0644)
+	assert.NoError(t, err)
+
+	keys, sprocs, err := ParseDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users;\n\n", keys["selectUsers"])
+	assert.Len(t, sprocs, 2)
+	assert.Contains(t, sprocs["f_users"], "create function f_users()")
+	assert.Contains(t, sprocs["f_posts"], "create function f_posts()")
+
+	err = ioutil.WriteFile(filepath.Join(dir, "dup.sql"), []byte("--@sproc=f_users\nselect 1;\n"), 0644)
+	assert.NoError(t, err)
+	_, _, err = ParseDir(dir)
+	assert.Error(t, err)
+}
@@ -72,16 +72,16 @@ func writeScopeCondition(buf common.BufferWriter, f *whereFragment, args *[]inte
 	}
 }
 
-func writeAndFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
-	return writeFragmentsToSQL(" AND ", true, buf, fragments, args, pos)
+func writeAndFragmentsToSQL(buf common.BufferWriter, dialect SQLDialect, strict bool, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	return writeFragmentsToSQL(" AND ", true, buf, dialect, strict, fragments, args, pos)
 }
 
-func writeCommaFragmentsToSQL(buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
-	return writeFragmentsToSQL(", ", false, buf, fragments, args, pos)
+func writeCommaFragmentsToSQL(buf common.BufferWriter, dialect SQLDialect, strict bool, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+	return writeFragmentsToSQL(", ", false, buf, dialect, strict, fragments, args, pos)
 }
 
 // Invariant: only called when len(fragments) > 0
-func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWriter, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
+func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWriter, dialect SQLDialect, strict bool, fragments []*whereFragment, args *[]interface{}, pos *int64) error {
 	hasConditions := false
 	for _, f := range fragments {
 		if f.Condition != "" {
@@ -107,7 +107,12 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 				buf.WriteRune(')')
 			}
 		} else if f.EqualityMap != nil {
-			hasConditions = writeEqualityMapToSQL(buf, f.EqualityMap, args, hasConditions, pos)
+			for k := range f.EqualityMap {
+				if err := checkColumns(strict, k); err != nil {
+					return err
+				}
+			}
+			hasConditions = writeEqualityMapToSQL(buf, dialect, f.EqualityMap, args, hasConditions, pos)
 		} else {
 			return NewError("invalid equality map")
 		}
@@ -116,10 +121,10 @@ func writeFragmentsToSQL(delimiter string, addParens bool, buf common.BufferWrit
 	return nil
 }
 
-func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, args *[]interface{}, anyConditions bool, pos *int64) bool {
+func writeEqualityMapToSQL(buf common.BufferWriter, dialect SQLDialect, eq map[string]interface{}, args *[]interface{}, anyConditions bool, pos *int64) bool {
 	for k, v := range eq {
 		if v == nil {
-			anyConditions = writeWhereCondition(buf, k, " IS NULL", anyConditions)
+			anyConditions = writeWhereCondition(buf, dialect, k, " IS NULL", anyConditions)
 		} else {
 			vVal := reflect.ValueOf(v)
 
@@ -127,7 +132,7 @@ func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, a
 				vValLen := vVal.Len()
 				if vValLen == 0 {
 					if vVal.IsNil() {
-						anyConditions = writeWhereCondition(buf, k, " IS NULL", anyConditions)
+						anyConditions = writeWhereCondition(buf, dialect, k, " IS NULL", anyConditions)
 					} else {
 						if anyConditions {
 							buf.WriteString(" AND (1=0)")
@@ -136,17 +141,17 @@ func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, a
 						}
 					}
 				} else if vValLen == 1 {
-					anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
+					anyConditions = writeWhereCondition(buf, dialect, k, equalsPlaceholderTab[*pos], anyConditions)
 					*args = append(*args, vVal.Index(0).Interface())
 					*pos++
 				} else {
 					// " IN $n"
-					anyConditions = writeWhereCondition(buf, k, inPlaceholderTab[*pos], anyConditions)
+					anyConditions = writeWhereCondition(buf, dialect, k, inPlaceholderTab[*pos], anyConditions)
 					*args = append(*args, v)
 					*pos++
 				}
 			} else {
-				anyConditions = writeWhereCondition(buf, k, equalsPlaceholderTab[*pos], anyConditions)
+				anyConditions = writeWhereCondition(buf, dialect, k, equalsPlaceholderTab[*pos], anyConditions)
 				*args = append(*args, v)
 				*pos++
 			}
@@ -156,14 +161,14 @@ func writeEqualityMapToSQL(buf common.BufferWriter, eq map[string]interface{}, a
 	return anyConditions
 }
 
-func writeWhereCondition(buf common.BufferWriter, k string, pred string, anyConditions bool) bool {
+func writeWhereCondition(buf common.BufferWriter, dialect SQLDialect, k string, pred string, anyConditions bool) bool {
 	if anyConditions {
 		buf.WriteString(" AND (")
 	} else {
 		buf.WriteRune('(')
 		anyConditions = true
 	}
-	writeIdentifier(buf, k)
+	dialect.WriteIdentifier(buf, k)
 	buf.WriteString(pred)
 	buf.WriteRune(')')
 
@@ -3,21 +3,26 @@ module github.com/nerdynz/dat
 go 1.22.4
 
 require (
+	github.com/alicebob/miniredis/v2 v2.35.0
 	github.com/cenkalti/backoff v2.2.1+incompatible
-	github.com/garyburd/redigo v1.6.0
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.4.0
+	github.com/mattn/go-sqlite3 v1.9.0
 	github.com/mgutz/jo v1.1.0
 	github.com/mgutz/str v1.2.0
 	github.com/oklog/ulid/v2 v2.1.0
 	github.com/pmylund/go-cache v2.1.0+incompatible
+	github.com/redis/go-redis/v9 v9.12.0
 	github.com/stretchr/testify v1.5.1
 )
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/mgutz/to v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
@@ -13,6 +13,24 @@ type KeyValueStore interface {
 	Get(key string) (string, error)
 	Del(key string) error
 	FlushDB() error
+
+	// MGet gets the values of keys in one round trip. The value of a key
+	// which does not exist is "".
+	MGet(keys ...string) ([]string, error)
+	// MSet sets many keys with the same time-to-live in one round trip.
+	MSet(values map[string]string, ttl time.Duration) error
+	// DelPrefix deletes all keys starting with prefix.
+	DelPrefix(prefix string) error
+	// SetNX sets a key only if it does not exist, returning whether it was set.
+	SetNX(key, value string, ttl time.Duration) (bool, error)
+	// Incr increments the integer value of a key by delta, returning the new
+	// value. A key which does not exist is set to delta and expires after ttl.
+	Incr(key string, delta int64, ttl time.Duration) (int64, error)
+	// TTL returns the time-to-live left of a key, TTLNever if it does not
+	// expire. It returns ErrNotFound if the key does not exist.
+	TTL(key string) (time.Duration, error)
+	// Close releases any resources held by the store.
+	Close() error
 }
 
 // TTLNever means do not expire a key
@@ -24,6 +42,12 @@ const NanosecondsPerMillisecond = 1000000
 // ErrNotFound is returned when an entry is not found in memory database.
 var ErrNotFound = errors.New("Key not found")
 
+// ErrInvalidOptions is returned when a store is created with conflicting options.
+var ErrInvalidOptions = errors.New("Invalid store options")
+
+// ErrSubscription is returned when a pub/sub subscription is not confirmed.
+var ErrSubscription = errors.New("Could not subscribe")
+
 // Hash returns the hash value of a string. The returned value is useful
 // as a key.
 func Hash(s string) string {
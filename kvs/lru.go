@@ -0,0 +1,307 @@
+package kvs
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LRUOptions are the options for an LRUStore.
+type LRUOptions struct {
+	// MaxEntries is the maximum number of keys held. 0 means unlimited.
+	MaxEntries int
+
+	// MaxBytes is the maximum combined length of keys and values held.
+	// 0 means unlimited.
+	MaxBytes int64
+
+	// CleanupInterval is how often expired keys are removed in the
+	// background. Expired keys are never returned regardless, 0 means they
+	// are only removed when accessed or evicted.
+	CleanupInterval time.Duration
+}
+
+// LRUStats are the counters of an LRUStore.
+type LRUStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+// LRUStore is a size bounded, in-memory KeyValueStore. When a bound is
+// exceeded the least recently used keys are evicted.
+type LRUStore struct {
+	sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	ll         *list.List
+	entries    map[string]*list.Element
+	bytes      int64
+	stats      LRUStats
+	stop       chan struct{}
+}
+
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+func (entry *lruEntry) size() int64 {
+	return int64(len(entry.key) + len(entry.value))
+}
+
+func (entry *lruEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && now.After(entry.expires)
+}
+
+// NewLRUStore creates an LRUStore.
+func NewLRUStore(opts LRUOptions) *LRUStore {
+	store := &LRUStore{
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+	}
+	if opts.CleanupInterval > 0 {
+		store.stop = make(chan struct{})
+		go store.cleanup(opts.CleanupInterval, store.stop)
+	}
+	return store
+}
+
+func (store *LRUStore) cleanup(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			store.DeleteExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Set sets a key with time-to-live. Use TTLNever to never expire. A value
+// larger than MaxBytes is not stored.
+func (store *LRUStore) Set(key, value string, ttl time.Duration) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.set(key, value, ttl)
+	return nil
+}
+
+// set sets a key, the caller must hold the lock.
+func (store *LRUStore) set(key, value string, ttl time.Duration) {
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := store.entries[key]; ok {
+		store.removeElement(el)
+	}
+	if store.maxBytes > 0 && entry.size() > store.maxBytes {
+		return
+	}
+
+	store.entries[key] = store.ll.PushFront(entry)
+	store.bytes += entry.size()
+	for store.overBounds() {
+		store.removeElement(store.ll.Back())
+		store.stats.Evictions++
+	}
+}
+
+func (store *LRUStore) overBounds() bool {
+	return (store.maxEntries > 0 && store.ll.Len() > store.maxEntries) ||
+		(store.maxBytes > 0 && store.bytes > store.maxBytes)
+}
+
+// Get retrieves a value given key. Returns ErrNotFound if the key does not
+// exist or has expired.
+func (store *LRUStore) Get(key string) (string, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	el, ok := store.entries[key]
+	if !ok {
+		store.stats.Misses++
+		return "", ErrNotFound
+	}
+	entry := el.Value.(*lruEntry)
+	if entry.expired(time.Now()) {
+		store.removeElement(el)
+		store.stats.Expirations++
+		store.stats.Misses++
+		return "", ErrNotFound
+	}
+	store.ll.MoveToFront(el)
+	store.stats.Hits++
+	return entry.value, nil
+}
+
+// Del deletes value given key.
+func (store *LRUStore) Del(key string) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if el, ok := store.entries[key]; ok {
+		store.removeElement(el)
+	}
+	return nil
+}
+
+// FlushDB clears all keys.
+func (store *LRUStore) FlushDB() error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.ll.Init()
+	store.entries = map[string]*list.Element{}
+	store.bytes = 0
+	return nil
+}
+
+// MGet retrieves the values of many keys.
+func (store *LRUStore) MGet(keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i], _ = store.Get(key)
+	}
+	return values, nil
+}
+
+// MSet sets many keys with time-to-live.
+func (store *LRUStore) MSet(values map[string]string, ttl time.Duration) error {
+	for key, value := range values {
+		store.Set(key, value, ttl)
+	}
+	return nil
+}
+
+// DelPrefix deletes all keys starting with prefix.
+func (store *LRUStore) DelPrefix(prefix string) error {
+	store.Lock()
+	defer store.Unlock()
+
+	for key, el := range store.entries {
+		if strings.HasPrefix(key, prefix) {
+			store.removeElement(el)
+		}
+	}
+	return nil
+}
+
+// SetNX sets a key with time-to-live only if it does not exist.
+func (store *LRUStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	if el, ok := store.entries[key]; ok && !el.Value.(*lruEntry).expired(time.Now()) {
+		return false, nil
+	}
+	store.set(key, value, ttl)
+	return true, nil
+}
+
+// Incr increments a key by delta. A new key expires after ttl.
+func (store *LRUStore) Incr(key string, delta int64, ttl time.Duration) (int64, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	el, ok := store.entries[key]
+	if !ok || el.Value.(*lruEntry).expired(time.Now()) {
+		store.set(key, strconv.FormatInt(delta, 10), ttl)
+		return delta, nil
+	}
+
+	entry := el.Value.(*lruEntry)
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n += delta
+	store.bytes -= entry.size()
+	entry.value = strconv.FormatInt(n, 10)
+	store.bytes += entry.size()
+	store.ll.MoveToFront(el)
+	for store.overBounds() {
+		store.removeElement(store.ll.Back())
+		store.stats.Evictions++
+	}
+	return n, nil
+}
+
+// TTL returns the time-to-live left of a key.
+func (store *LRUStore) TTL(key string) (time.Duration, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	el, ok := store.entries[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	entry := el.Value.(*lruEntry)
+	if entry.expires.IsZero() {
+		return TTLNever, nil
+	}
+	remaining := time.Until(entry.expires)
+	if remaining <= 0 {
+		return 0, ErrNotFound
+	}
+	return remaining, nil
+}
+
+// DeleteExpired removes all expired keys.
+func (store *LRUStore) DeleteExpired() {
+	store.Lock()
+	defer store.Unlock()
+
+	now := time.Now()
+	for el := store.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if el.Value.(*lruEntry).expired(now) {
+			store.removeElement(el)
+			store.stats.Expirations++
+		}
+		el = prev
+	}
+}
+
+// Stats returns a snapshot of the store's counters.
+func (store *LRUStore) Stats() LRUStats {
+	store.Lock()
+	defer store.Unlock()
+
+	stats := store.stats
+	stats.Entries = store.ll.Len()
+	stats.Bytes = store.bytes
+	return stats
+}
+
+// Close stops the background cleanup.
+func (store *LRUStore) Close() error {
+	store.Lock()
+	defer store.Unlock()
+
+	if store.stop != nil {
+		close(store.stop)
+		store.stop = nil
+	}
+	return nil
+}
+
+func (store *LRUStore) removeElement(el *list.Element) {
+	entry := el.Value.(*lruEntry)
+	store.ll.Remove(el)
+	delete(store.entries, entry.key)
+	store.bytes -= entry.size()
+}
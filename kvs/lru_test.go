@@ -0,0 +1,103 @@
+package kvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsByEntries(t *testing.T) {
+	store := NewLRUStore(LRUOptions{MaxEntries: 2})
+	store.Set("a", "1", TTLNever)
+	store.Set("b", "2", TTLNever)
+	store.Get("a")
+	store.Set("c", "3", TTLNever)
+
+	_, err := store.Get("b")
+	assert.Equal(t, ErrNotFound, err)
+	v, err := store.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", v)
+
+	stats := store.Stats()
+	assert.EqualValues(t, 2, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.EqualValues(t, 1, stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+}
+
+func TestLRUEvictsByBytes(t *testing.T) {
+	store := NewLRUStore(LRUOptions{MaxBytes: 10})
+	store.Set("a", "1234", TTLNever)
+	store.Set("b", "1234", TTLNever)
+	assert.EqualValues(t, 10, store.Stats().Bytes)
+
+	store.Set("c", "1", TTLNever)
+	_, err := store.Get("a")
+	assert.Equal(t, ErrNotFound, err)
+	assert.EqualValues(t, 7, store.Stats().Bytes)
+
+	// too large to ever fit
+	store.Set("d", "12345678901", TTLNever)
+	_, err = store.Get("d")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 2, store.Stats().Entries)
+}
+
+func TestLRUExpires(t *testing.T) {
+	store := NewLRUStore(LRUOptions{})
+	store.Set("a", "1", time.Millisecond)
+	store.Set("b", "2", time.Millisecond)
+	store.Set("c", "3", time.Minute)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := store.Get("a")
+	assert.Equal(t, ErrNotFound, err)
+
+	store.DeleteExpired()
+	stats := store.Stats()
+	assert.EqualValues(t, 2, stats.Expirations)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestLRUFlushDB(t *testing.T) {
+	store := NewLRUStore(LRUOptions{CleanupInterval: time.Millisecond})
+	defer store.Close()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			store.Set("a", "1", time.Minute)
+		}
+		done <- true
+	}()
+	store.FlushDB()
+	<-done
+
+	store.FlushDB()
+	assert.Equal(t, LRUStats{}, store.Stats())
+}
+
+func TestLRUSetNXAndIncr(t *testing.T) {
+	store := NewLRUStore(LRUOptions{})
+
+	ok, _ := store.SetNX("lock", "a", time.Minute)
+	assert.True(t, ok)
+	ok, _ = store.SetNX("lock", "b", time.Minute)
+	assert.False(t, ok)
+
+	n, err := store.Incr("hits", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	n, err = store.Incr("hits", -1, time.Minute)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	_, err = store.Incr("lock", 1, time.Minute)
+	assert.Error(t, err)
+
+	store.DelPrefix("hi")
+	values, _ := store.MGet("lock", "hits")
+	assert.Equal(t, []string{"a", ""}, values)
+}
@@ -1,6 +1,9 @@
 package kvs
 
 import (
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nerdynz/dat/internal/log"
@@ -11,6 +14,9 @@ import (
 type MemoryKeyValueStore struct {
 	Cache           *gocache.Cache
 	cleanupInterval time.Duration
+
+	// incrMu makes the read-modify-write of Incr atomic
+	incrMu sync.Mutex
 }
 
 // NewDefaultMemoryStore creates an instance of MemoryKeyValueStore
@@ -56,6 +62,85 @@ func (store *MemoryKeyValueStore) Del(key string) error {
 
 // FlushDB clears all keys
 func (store *MemoryKeyValueStore) FlushDB() error {
-	store.Cache = gocache.New(gocache.NoExpiration, store.cleanupInterval)
+	store.Cache.Flush()
+	return nil
+}
+
+// MGet retrieves the values of many keys.
+func (store *MemoryKeyValueStore) MGet(keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i], _ = store.Get(key)
+	}
+	return values, nil
+}
+
+// MSet sets many keys with time-to-live.
+func (store *MemoryKeyValueStore) MSet(values map[string]string, ttl time.Duration) error {
+	for key, value := range values {
+		store.Set(key, value, ttl)
+	}
+	return nil
+}
+
+// DelPrefix deletes all keys starting with prefix.
+func (store *MemoryKeyValueStore) DelPrefix(prefix string) error {
+	for key := range store.Cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			store.Cache.Delete(key)
+		}
+	}
+	return nil
+}
+
+// SetNX sets a key with time-to-live only if it does not exist.
+func (store *MemoryKeyValueStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	return store.Cache.Add(key, value, ttl) == nil, nil
+}
+
+// Incr increments a key by delta. A new key expires after ttl.
+func (store *MemoryKeyValueStore) Incr(key string, delta int64, ttl time.Duration) (int64, error) {
+	store.incrMu.Lock()
+	defer store.incrMu.Unlock()
+
+	val, expires, found := store.Cache.GetWithExpiration(key)
+	// keep the expiration of the existing key. go-cache never expires a key
+	// set with a negative duration, so a key expiring now is reset instead.
+	remaining := gocache.NoExpiration
+	if found && !expires.IsZero() {
+		remaining = time.Until(expires)
+		found = remaining > 0
+	}
+	if !found {
+		store.Cache.Set(key, strconv.FormatInt(delta, 10), ttl)
+		return delta, nil
+	}
+	n, err := strconv.ParseInt(val.(string), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n += delta
+	store.Cache.Set(key, strconv.FormatInt(n, 10), remaining)
+	return n, nil
+}
+
+// TTL returns the time-to-live left of a key.
+func (store *MemoryKeyValueStore) TTL(key string) (time.Duration, error) {
+	_, expires, found := store.Cache.GetWithExpiration(key)
+	if !found {
+		return 0, ErrNotFound
+	}
+	if expires.IsZero() {
+		return TTLNever, nil
+	}
+	remaining := time.Until(expires)
+	if remaining <= 0 {
+		return 0, ErrNotFound
+	}
+	return remaining, nil
+}
+
+// Close is a noop for the in-memory store.
+func (store *MemoryKeyValueStore) Close() error {
 	return nil
 }
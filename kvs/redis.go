@@ -1,36 +1,98 @@
 package kvs
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"time"
 
-	"github.com/garyburd/redigo/redis"
 	"github.com/nerdynz/dat/internal/log"
+	"github.com/redis/go-redis/v9"
 )
 
-func newRedisPool(host, password string) *redis.Pool {
-	return &redis.Pool{
-		MaxIdle:     3,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			c, err := redis.Dial("tcp", host)
-			if err != nil {
-				return nil, err
-			}
-			if password != "" {
-				if _, err = c.Do("AUTH", password); err != nil {
-					c.Close()
-					return nil, err
-				}
-			}
-			return c, err
-		},
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
+// RedisOptions are the options for a RedisStore.
+type RedisOptions struct {
+	// Namespace is the prefix for keys, keys are stored as "Namespace:key".
+	Namespace string
+
+	// Addrs are the host:port addresses of the server. A single address
+	// connects to a standalone server, several addresses to a Cluster. When
+	// MasterName is set, these are the addresses of the Sentinels.
+	Addrs []string
+
+	// Cluster connects to a Cluster even if only a single seed address is
+	// given.
+	Cluster bool
+
+	// MasterName is the name of the master monitored by Sentinel.
+	MasterName string
+
+	// Username and Password authenticate with AUTH. Username requires
+	// Redis 6+ ACLs.
+	Username string
+	Password string
+
+	// SentinelUsername and SentinelPassword authenticate with Sentinel.
+	SentinelUsername string
+	SentinelPassword string
+
+	// DB is the database selected after connecting. Not supported by Cluster.
+	DB int
+
+	// TLSConfig enables TLS when set.
+	TLSConfig *tls.Config
+
+	// PoolSize is the maximum number of connections per node. Defaults to
+	// 10 connections per CPU.
+	PoolSize int
+	// MinIdleConns is the minimum number of idle connections kept open.
+	MinIdleConns int
+	// MaxIdleConns is the maximum number of idle connections kept open.
+	MaxIdleConns int
+	// ConnMaxIdleTime closes connections idle for longer. Defaults to 30
+	// minutes.
+	ConnMaxIdleTime time.Duration
+
+	// DialTimeout, ReadTimeout and WriteTimeout are the socket timeouts.
+	// They default to 5s, 3s and ReadTimeout respectively.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// CommandTimeout bounds each operation of the store, including retries.
+	// 0 means no limit beyond the socket timeouts.
+	CommandTimeout time.Duration
+}
+
+func (opts *RedisOptions) universal() *redis.UniversalOptions {
+	return &redis.UniversalOptions{
+		Addrs:                 opts.Addrs,
+		IsClusterMode:         opts.Cluster,
+		MasterName:            opts.MasterName,
+		Username:              opts.Username,
+		Password:              opts.Password,
+		SentinelUsername:      opts.SentinelUsername,
+		SentinelPassword:      opts.SentinelPassword,
+		DB:                    opts.DB,
+		TLSConfig:             opts.TLSConfig,
+		PoolSize:              opts.PoolSize,
+		MinIdleConns:          opts.MinIdleConns,
+		MaxIdleConns:          opts.MaxIdleConns,
+		ConnMaxIdleTime:       opts.ConnMaxIdleTime,
+		DialTimeout:           opts.DialTimeout,
+		ReadTimeout:           opts.ReadTimeout,
+		WriteTimeout:          opts.WriteTimeout,
+		ContextTimeoutEnabled: opts.CommandTimeout > 0,
 	}
 }
 
+func newRedisClient(opts *RedisOptions) redis.UniversalClient {
+	if len(opts.Addrs) == 0 {
+		opts.Addrs = []string{":6379"}
+	}
+	return redis.NewUniversalClient(opts.universal())
+}
+
 ////////////////////////////////////////
 
 // NewDefaultRedisStore instantiates Redis.
@@ -38,48 +100,81 @@ func NewDefaultRedisStore() (KeyValueStore, error) {
 	return NewRedisStore("", ":6379", "")
 }
 
-// NewRedisStore creates a new instance of RedisTokenStore.
+// NewRedisStore creates a new instance of RedisStore for a standalone server.
 func NewRedisStore(ns string, host string, password string) (*RedisStore, error) {
-	log.Debug("Creating redis pool", "ns", ns, "host", host, "usingPassword", password == "")
-	pool := newRedisPool(host, password)
-	return NewRedisStoreFromPool(ns, pool), nil
+	return NewRedisStoreWithOptions(RedisOptions{
+		Namespace: ns,
+		Addrs:     []string{host},
+		Password:  password,
+	})
 }
 
-// NewRedisStoreFromPool creates a new instance of RedisTokenStore from an existing pool.
-func NewRedisStoreFromPool(ns string, pool *redis.Pool) *RedisStore {
-	return &RedisStore{ns: ns + ":", pool: pool}
+// NewRedisStoreWithOptions creates a new instance of RedisStore.
+func NewRedisStoreWithOptions(opts RedisOptions) (*RedisStore, error) {
+	log.Debug("Creating redis client", "ns", opts.Namespace, "addrs", opts.Addrs, "usingPassword", opts.Password != "")
+	if opts.Cluster && opts.DB != 0 {
+		return nil, ErrInvalidOptions
+	}
+	client := newRedisClient(&opts)
+	store := NewRedisStoreFromClient(opts.Namespace, client)
+	store.timeout = opts.CommandTimeout
+	return store, nil
+}
+
+// NewRedisStoreFromClient creates a new instance of RedisStore from an
+// existing client.
+func NewRedisStoreFromClient(ns string, client redis.UniversalClient) *RedisStore {
+	return &RedisStore{ns: ns + ":", client: client}
 }
 
 // RedisStore is a concrete implementation of KeyValueStore for Redis.
 type RedisStore struct {
-	pool *redis.Pool
-	ns   string
+	client  redis.UniversalClient
+	ns      string
+	timeout time.Duration
 }
 
-// Set sets a key's value with TTL. Use cache.TTLNever to never expire.
-func (rs *RedisStore) Set(key, value string, ttl time.Duration) error {
-	conn := rs.pool.Get()
-	defer conn.Close()
-	var err error
+// Client returns the underlying client.
+func (rs *RedisStore) Client() redis.UniversalClient {
+	return rs.client
+}
 
-	key = rs.ns + key
+// context returns the context for a single operation.
+func (rs *RedisStore) context() (context.Context, context.CancelFunc) {
+	return commandContext(rs.timeout)
+}
+
+// commandContext returns the context of a command bounded by timeout, if
+// any. See RedisOptions.CommandTimeout.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.Background(), func() {}
+}
 
+// expiration converts a TTL to a go-redis expiration.
+func expiration(ttl time.Duration) time.Duration {
 	if ttl == TTLNever {
-		_, err = conn.Do("SET", key, value)
-	} else {
-		_, err = conn.Do("SET", key, value, "PX", ttl.Nanoseconds()/NanosecondsPerMillisecond)
+		return 0
 	}
-	return err
+	return ttl
+}
+
+// Set sets a key's value with TTL. Use cache.TTLNever to never expire.
+func (rs *RedisStore) Set(key, value string, ttl time.Duration) error {
+	ctx, cancel := rs.context()
+	defer cancel()
+	return rs.client.Set(ctx, rs.ns+key, value, expiration(ttl)).Err()
 }
 
 // Get gets
 func (rs *RedisStore) Get(key string) (string, error) {
-	conn := rs.pool.Get()
-	defer conn.Close()
+	ctx, cancel := rs.context()
+	defer cancel()
 
-	key = rs.ns + key
-	s, err := redis.String(conn.Do("GET", key))
-	if err == redis.ErrNil {
+	s, err := rs.client.Get(ctx, rs.ns+key).Result()
+	if err == redis.Nil {
 		return "", ErrNotFound
 	} else if err != nil {
 		return "", err
@@ -89,17 +184,168 @@ func (rs *RedisStore) Get(key string) (string, error) {
 
 // Del deletes a key
 func (rs *RedisStore) Del(key string) error {
-	conn := rs.pool.Get()
-	defer conn.Close()
-	key = rs.ns + key
-	_, err := conn.Do("DEL", key)
-	return err
+	ctx, cancel := rs.context()
+	defer cancel()
+	return rs.client.Del(ctx, rs.ns+key).Err()
 }
 
-// FlushDB removes all keys.
+// FlushDB removes all keys in this store's namespace. Keys outside of the
+// namespace are left alone.
 func (rs *RedisStore) FlushDB() error {
-	conn := rs.pool.Get()
-	defer conn.Close()
-	_, err := conn.Do("FLUSHDB")
+	return rs.DelPrefix("")
+}
+
+// MGet gets the values of many keys in a single pipeline. Keys may live on
+// different Cluster nodes.
+func (rs *RedisStore) MGet(keys ...string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := rs.context()
+	defer cancel()
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, rs.ns+key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		values[i] = cmd.Val()
+	}
+	return values, nil
+}
+
+// MSet sets many keys with TTL in a single pipeline.
+func (rs *RedisStore) MSet(values map[string]string, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+	ctx, cancel := rs.context()
+	defer cancel()
+
+	_, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(ctx, rs.ns+key, value, expiration(ttl))
+		}
+		return nil
+	})
 	return err
 }
+
+// DelPrefix deletes all keys starting with prefix.
+func (rs *RedisStore) DelPrefix(prefix string) error {
+	return rs.DelPattern(escapePattern(prefix) + "*")
+}
+
+// DelPattern deletes all keys matching the glob-style pattern, see
+// https://redis.io/commands/keys. The pattern is matched within this store's
+// namespace. Keys are found with SCAN so Redis is not blocked. On a Cluster
+// every master is scanned.
+func (rs *RedisStore) DelPattern(pattern string) error {
+	ctx, cancel := rs.context()
+	defer cancel()
+
+	pattern = escapePattern(rs.ns) + pattern
+	if cluster, ok := rs.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return delPattern(ctx, client, pattern)
+		})
+	}
+	return delPattern(ctx, rs.client, pattern)
+}
+
+func delPattern(ctx context.Context, client redis.Cmdable, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return err
+		}
+		// delete one at a time, keys may hash to different Cluster slots
+		for _, key := range keys {
+			if err := client.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// escapePattern escapes the special characters of a glob-style pattern.
+func escapePattern(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// SetNX sets a key's value with TTL only if the key does not exist.
+func (rs *RedisStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	ctx, cancel := rs.context()
+	defer cancel()
+	return rs.client.SetNX(ctx, rs.ns+key, value, expiration(ttl)).Result()
+}
+
+// incrScript increments a key, setting the expiry only if the key was created.
+var incrScript = redis.NewScript(`
+local exists = redis.call('EXISTS', KEYS[1])
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if exists == 0 and tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n
+`)
+
+// Incr increments a key by delta. A new key expires after ttl.
+func (rs *RedisStore) Incr(key string, delta int64, ttl time.Duration) (int64, error) {
+	ctx, cancel := rs.context()
+	defer cancel()
+
+	var ms int64 = -1
+	if ttl != TTLNever {
+		ms = ttl.Nanoseconds() / NanosecondsPerMillisecond
+	}
+	return incrScript.Run(ctx, rs.client, []string{rs.ns + key}, delta, ms).Int64()
+}
+
+// TTL returns the time-to-live left of a key.
+func (rs *RedisStore) TTL(key string) (time.Duration, error) {
+	ctx, cancel := rs.context()
+	defer cancel()
+
+	ttl, err := rs.client.PTTL(ctx, rs.ns+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL replies -2 for a missing key and -1 for a key without expiry
+	switch ttl {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return TTLNever, nil
+	}
+	return ttl, nil
+}
+
+// Close closes the client and its connections.
+func (rs *RedisStore) Close() error {
+	return rs.client.Close()
+}
@@ -0,0 +1,100 @@
+package kvs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisInvalidator is an Invalidator over Redis pub/sub.
+type RedisInvalidator struct {
+	sync.Mutex
+	client  redis.UniversalClient
+	channel string
+	pubsub  *redis.PubSub
+	timeout time.Duration
+}
+
+// NewRedisInvalidator creates a RedisInvalidator publishing to channel on a
+// standalone server.
+func NewRedisInvalidator(channel string, host string, password string) *RedisInvalidator {
+	return NewRedisInvalidatorFromClient(channel, newRedisClient(&RedisOptions{
+		Addrs:    []string{host},
+		Password: password,
+	}))
+}
+
+// NewRedisInvalidatorWithOptions creates a RedisInvalidator publishing to
+// channel. Publish is bounded by opts.CommandTimeout.
+func NewRedisInvalidatorWithOptions(channel string, opts RedisOptions) *RedisInvalidator {
+	ri := NewRedisInvalidatorFromClient(channel, newRedisClient(&opts))
+	ri.timeout = opts.CommandTimeout
+	return ri
+}
+
+// NewRedisInvalidatorFromStore creates a RedisInvalidator sharing the client
+// and command timeout of store.
+func NewRedisInvalidatorFromStore(channel string, store *RedisStore) *RedisInvalidator {
+	ri := NewRedisInvalidatorFromClient(channel, store.client)
+	ri.timeout = store.timeout
+	return ri
+}
+
+// NewRedisInvalidatorFromClient creates a RedisInvalidator from an existing
+// client such as RedisStore.Client().
+func NewRedisInvalidatorFromClient(channel string, client redis.UniversalClient) *RedisInvalidator {
+	return &RedisInvalidator{client: client, channel: channel}
+}
+
+// Publish publishes message to the channel.
+func (ri *RedisInvalidator) Publish(message string) error {
+	ctx, cancel := commandContext(ri.timeout)
+	defer cancel()
+	return ri.client.Publish(ctx, ri.channel, message).Err()
+}
+
+// Subscribe subscribes to the channel on a dedicated connection, calling fn
+// for each message received until Close is called.
+func (ri *RedisInvalidator) Subscribe(fn func(message string)) error {
+	ri.Lock()
+	defer ri.Unlock()
+
+	ctx := context.Background()
+	pubsub := ri.client.Subscribe(ctx, ri.channel)
+	// wait for the subscription to be confirmed so no message published
+	// after Subscribe returns is missed
+	reply, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return err
+	}
+	if _, ok := reply.(*redis.Subscription); !ok {
+		pubsub.Close()
+		return ErrSubscription
+	}
+	ri.pubsub = pubsub
+
+	// the channel is closed by pubsub.Close, go-redis reconnects on errors
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			fn(msg.Payload)
+		}
+	}()
+	return nil
+}
+
+// Close closes the subscription.
+func (ri *RedisInvalidator) Close() error {
+	ri.Lock()
+	defer ri.Unlock()
+
+	if ri.pubsub == nil {
+		return nil
+	}
+	pubsub := ri.pubsub
+	ri.pubsub = nil
+	return pubsub.Close()
+}
@@ -0,0 +1,162 @@
+package kvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	store, err := NewRedisStore("test", mr.Addr(), "")
+	assert.NoError(t, err)
+	return store, mr
+}
+
+func TestRedisFlushDBIsNamespaced(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	defer store.Close()
+
+	mr.Set("other:foo", "bar")
+	assert.NoError(t, store.Set("foo", "bar", TTLNever))
+	assert.NoError(t, store.FlushDB())
+
+	assert.False(t, mr.Exists("test:foo"))
+	assert.True(t, mr.Exists("other:foo"))
+}
+
+func TestRedisMSetMGet(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	defer store.Close()
+
+	err := store.MSet(map[string]string{"a": "1", "b": "2"}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, mr.TTL("test:a"))
+
+	values, err := store.MGet("a", "missing", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "", "2"}, values)
+}
+
+func TestRedisDelPrefix(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	defer store.Close()
+
+	store.Set("user:1", "a", TTLNever)
+	store.Set("user:2", "b", TTLNever)
+	store.Set("post:1", "c", TTLNever)
+	store.Set("user*", "d", TTLNever)
+
+	assert.NoError(t, store.DelPrefix("user:"))
+	assert.False(t, mr.Exists("test:user:1"))
+	assert.False(t, mr.Exists("test:user:2"))
+	assert.True(t, mr.Exists("test:post:1"))
+	assert.True(t, mr.Exists("test:user*"))
+}
+
+func TestRedisSetNX(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	defer store.Close()
+
+	ok, err := store.SetNX("lock", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.SetNX("lock", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	v, _ := store.Get("lock")
+	assert.Equal(t, "a", v)
+}
+
+func TestRedisIncr(t *testing.T) {
+	store, mr := newTestRedisStore(t)
+	defer store.Close()
+
+	n, err := store.Incr("hits", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.Equal(t, time.Minute, mr.TTL("test:hits"))
+
+	mr.SetTTL("test:hits", time.Second)
+	n, err = store.Incr("hits", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, n)
+	// the expiry of an existing key is kept
+	assert.Equal(t, time.Second, mr.TTL("test:hits"))
+}
+
+func TestRedisOptionsAuthAndDB(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireUserAuth("dat", "secret")
+
+	store, err := NewRedisStoreWithOptions(RedisOptions{
+		Namespace:      "test",
+		Addrs:          []string{mr.Addr()},
+		Username:       "dat",
+		Password:       "secret",
+		DB:             2,
+		PoolSize:       2,
+		CommandTimeout: time.Second,
+	})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.NoError(t, store.Set("foo", "bar", TTLNever))
+	v, err := mr.DB(2).Get("test:foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	assert.False(t, mr.Exists("test:foo"))
+
+	bad, err := NewRedisStoreWithOptions(RedisOptions{
+		Addrs:    []string{mr.Addr()},
+		Username: "dat",
+		Password: "wrong",
+	})
+	assert.NoError(t, err)
+	defer bad.Close()
+	assert.Error(t, bad.Set("foo", "bar", TTLNever))
+}
+
+func TestRedisCluster(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	store, err := NewRedisStoreWithOptions(RedisOptions{
+		Namespace: "test",
+		Addrs:     []string{mr.Addr()},
+		Cluster:   true,
+	})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.NoError(t, store.MSet(map[string]string{"a": "1", "b": "2"}, time.Minute))
+	values, err := store.MGet("a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	assert.NoError(t, store.FlushDB())
+	assert.False(t, mr.Exists("test:a"))
+
+	_, err = NewRedisStoreWithOptions(RedisOptions{Cluster: true, DB: 1})
+	assert.Equal(t, ErrInvalidOptions, err)
+}
+
+func TestRedisTTL(t *testing.T) {
+	store, _ := newTestRedisStore(t)
+	defer store.Close()
+
+	assert.NoError(t, store.Set("expiring", "a", time.Minute))
+	assert.NoError(t, store.Set("forever", "b", TTLNever))
+
+	ttl, err := store.TTL("expiring")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+	ttl, err = store.TTL("forever")
+	assert.NoError(t, err)
+	assert.Equal(t, TTLNever, ttl)
+	_, err = store.TTL("missing")
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,269 @@
+package kvs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nerdynz/dat/internal/log"
+	"github.com/oklog/ulid/v2"
+)
+
+// Invalidator broadcasts changed keys to every TieredStore sharing the same
+// remote store, so they can drop stale local entries.
+type Invalidator interface {
+	// Publish announces that message changed.
+	Publish(message string) error
+	// Subscribe calls fn with every message published, including those
+	// published by this process.
+	Subscribe(fn func(message string)) error
+	// Close stops the subscription.
+	Close() error
+}
+
+// TieredOptions are the options for a TieredStore.
+type TieredOptions struct {
+	// LocalSize is the maximum number of entries held in the local tier.
+	// Defaults to 1024.
+	LocalSize int
+
+	// LocalMaxBytes is the maximum combined length of keys and values held
+	// in the local tier. 0 means unlimited.
+	LocalMaxBytes int64
+
+	// LocalTTL is the longest time an entry is served from the local tier
+	// before it is read again from the remote tier. Defaults to 5 seconds.
+	LocalTTL time.Duration
+
+	// Invalidator propagates Set, Del and FlushDB to other processes. Without
+	// one, other processes may serve stale entries for up to LocalTTL.
+	Invalidator Invalidator
+}
+
+// TieredStore is a KeyValueStore which reads through a small LRUStore
+// (the local tier) to a shared store such as Redis (the remote tier). Writes
+// go to both tiers.
+type TieredStore struct {
+	local       *LRUStore
+	localTTL    time.Duration
+	remote      KeyValueStore
+	invalidator Invalidator
+	origin      string
+}
+
+// Invalidation messages are tagged with the kind of invalidation so that
+// keys are never mistaken for prefixes. FlushDB publishes the prefix "".
+const (
+	invalidateKey    = "key"
+	invalidatePrefix = "prefix"
+)
+
+// NewTieredStore creates a TieredStore in front of remote.
+func NewTieredStore(remote KeyValueStore, opts TieredOptions) (*TieredStore, error) {
+	if opts.LocalSize <= 0 {
+		opts.LocalSize = 1024
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = 5 * time.Second
+	}
+
+	store := &TieredStore{
+		local:       NewLRUStore(LRUOptions{MaxEntries: opts.LocalSize, MaxBytes: opts.LocalMaxBytes}),
+		localTTL:    opts.LocalTTL,
+		remote:      remote,
+		invalidator: opts.Invalidator,
+		origin:      ulid.Make().String(),
+	}
+
+	if store.invalidator != nil {
+		err := store.invalidator.Subscribe(store.onInvalidate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// onInvalidate clears the local tier for messages published by other stores.
+// Messages are formatted as "origin kind key".
+func (store *TieredStore) onInvalidate(message string) {
+	parts := strings.SplitN(message, " ", 3)
+	if len(parts) != 3 || parts[0] == store.origin {
+		return
+	}
+
+	switch key := parts[2]; parts[1] {
+	case invalidateKey:
+		store.local.Del(key)
+	case invalidatePrefix:
+		store.local.DelPrefix(key)
+	}
+}
+
+func (store *TieredStore) publish(kind, key string) {
+	if store.invalidator == nil {
+		return
+	}
+	err := store.invalidator.Publish(store.origin + " " + kind + " " + key)
+	if err != nil {
+		log.Error("Could not publish cache invalidation", "key", key, "err", err)
+	}
+}
+
+// Set sets a key in both tiers. The local tier keeps the key for at most
+// LocalTTL.
+func (store *TieredStore) Set(key, value string, ttl time.Duration) error {
+	err := store.remote.Set(key, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	store.local.Set(key, value, store.capTTL(ttl))
+	store.publish(invalidateKey, key)
+	return nil
+}
+
+// capTTL caps ttl to LocalTTL.
+func (store *TieredStore) capTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < store.localTTL {
+		return ttl
+	}
+	return store.localTTL
+}
+
+// Get retrieves a value from the local tier, falling back to the remote tier.
+// A value read from the remote tier is kept locally for at most the
+// time-to-live it has left remotely, since its expiry is not broadcast.
+func (store *TieredStore) Get(key string) (string, error) {
+	if val, err := store.local.Get(key); err == nil {
+		return val, nil
+	}
+
+	val, err := store.remote.Get(key)
+	if err != nil || val == "" {
+		return val, err
+	}
+	store.setLocal(key, val)
+	return val, nil
+}
+
+// setLocal keeps a value read from the remote tier in the local tier until
+// it expires remotely or for LocalTTL, whichever comes first.
+func (store *TieredStore) setLocal(key, value string) {
+	ttl, err := store.remote.TTL(key)
+	if err != nil {
+		return
+	}
+	store.local.Set(key, value, store.capTTL(ttl))
+}
+
+// TTL returns the time-to-live left of a key in the remote tier.
+func (store *TieredStore) TTL(key string) (time.Duration, error) {
+	return store.remote.TTL(key)
+}
+
+// Del deletes a key from both tiers.
+func (store *TieredStore) Del(key string) error {
+	store.local.Del(key)
+	err := store.remote.Del(key)
+	store.publish(invalidateKey, key)
+	return err
+}
+
+// FlushDB clears both tiers.
+func (store *TieredStore) FlushDB() error {
+	store.local.FlushDB()
+	err := store.remote.FlushDB()
+	store.publish(invalidatePrefix, "")
+	return err
+}
+
+// MGet retrieves the values of many keys, reading only the keys missing
+// from the local tier from the remote tier.
+func (store *TieredStore) MGet(keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	var missing []string
+	var missingIdx []int
+	for i, key := range keys {
+		val, err := store.local.Get(key)
+		if err != nil {
+			missing = append(missing, key)
+			missingIdx = append(missingIdx, i)
+			continue
+		}
+		values[i] = val
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	remote, err := store.remote.MGet(missing...)
+	if err != nil {
+		return nil, err
+	}
+	for i, val := range remote {
+		values[missingIdx[i]] = val
+		if val != "" {
+			store.setLocal(missing[i], val)
+		}
+	}
+	return values, nil
+}
+
+// MSet sets many keys in both tiers.
+func (store *TieredStore) MSet(values map[string]string, ttl time.Duration) error {
+	err := store.remote.MSet(values, ttl)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		store.local.Set(key, value, store.capTTL(ttl))
+		store.publish(invalidateKey, key)
+	}
+	return nil
+}
+
+// DelPrefix deletes all keys starting with prefix from both tiers.
+func (store *TieredStore) DelPrefix(prefix string) error {
+	store.local.DelPrefix(prefix)
+	err := store.remote.DelPrefix(prefix)
+	store.publish(invalidatePrefix, prefix)
+	return err
+}
+
+// SetNX sets a key in both tiers only if it does not exist in the remote tier.
+func (store *TieredStore) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	ok, err := store.remote.SetNX(key, value, ttl)
+	if err != nil || !ok {
+		return ok, err
+	}
+	store.local.Set(key, value, store.capTTL(ttl))
+	store.publish(invalidateKey, key)
+	return true, nil
+}
+
+// Incr increments a key in the remote tier. Counters are never served from
+// the local tier.
+func (store *TieredStore) Incr(key string, delta int64, ttl time.Duration) (int64, error) {
+	n, err := store.remote.Incr(key, delta, ttl)
+	if err != nil {
+		return 0, err
+	}
+	store.local.Del(key)
+	store.publish(invalidateKey, key)
+	return n, nil
+}
+
+// LocalStats returns the counters of the local tier.
+func (store *TieredStore) LocalStats() LRUStats {
+	return store.local.Stats()
+}
+
+// Close stops listening for invalidations and closes the remote store.
+func (store *TieredStore) Close() error {
+	if store.invalidator != nil {
+		if err := store.invalidator.Close(); err != nil {
+			return err
+		}
+	}
+	return store.remote.Close()
+}
@@ -0,0 +1,166 @@
+package kvs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTieredStore(t *testing.T, mr *miniredis.Miniredis) *TieredStore {
+	remote, err := NewRedisStore("test", mr.Addr(), "")
+	assert.NoError(t, err)
+	store, err := NewTieredStore(remote, TieredOptions{
+		LocalTTL:    time.Minute,
+		Invalidator: NewRedisInvalidator("dat:invalidate", mr.Addr(), ""),
+	})
+	assert.NoError(t, err)
+	return store
+}
+
+func TestTieredReadThrough(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newTestTieredStore(t, mr)
+	defer store.Close()
+
+	assert.NoError(t, store.Set("foo", "bar", time.Minute))
+	v, err := mr.Get("test:foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	// served from the local tier even though the remote tier changed
+	mr.Set("test:foo", "baz")
+	v, err = store.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	store.local.FlushDB()
+	v, err = store.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", v)
+
+	_, err = store.Get("missing")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestTieredInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTestTieredStore(t, mr)
+	defer a.Close()
+	b := newTestTieredStore(t, mr)
+	defer b.Close()
+
+	assert.NoError(t, a.Set("foo", "bar", time.Minute))
+	v, err := b.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	assert.NoError(t, a.Set("foo", "baz", time.Minute))
+	assert.Eventually(t, func() bool {
+		v, _ := b.Get("foo")
+		return v == "baz"
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, a.Del("foo"))
+	assert.Eventually(t, func() bool {
+		_, err := b.local.Get("foo")
+		return err == ErrNotFound
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredDelPrefixInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTestTieredStore(t, mr)
+	defer a.Close()
+	b := newTestTieredStore(t, mr)
+	defer b.Close()
+
+	assert.NoError(t, a.MSet(map[string]string{"user:1": "a", "user:2": "b"}, time.Minute))
+	values, err := b.MGet("user:1", "user:2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, values)
+
+	assert.NoError(t, a.DelPrefix("user:"))
+	assert.Eventually(t, func() bool {
+		return b.LocalStats().Entries == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredWildcardKeyInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTestTieredStore(t, mr)
+	defer a.Close()
+	b := newTestTieredStore(t, mr)
+	defer b.Close()
+
+	assert.NoError(t, a.MSet(map[string]string{"foo*": "a", "foo1": "b"}, time.Minute))
+	_, err := b.MGet("foo*", "foo1")
+	assert.NoError(t, err)
+
+	// a key ending in * is deleted as a key, not as a prefix
+	assert.NoError(t, a.Del("foo*"))
+	assert.Eventually(t, func() bool {
+		_, err := b.local.Get("foo*")
+		return err == ErrNotFound
+	}, time.Second, 10*time.Millisecond)
+	v, err := b.local.Get("foo1")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+}
+
+func TestTieredLocalTTLCappedByRemote(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newTestTieredStore(t, mr)
+	defer store.Close()
+
+	mr.Set("test:foo", "bar")
+	mr.SetTTL("test:foo", 100*time.Millisecond)
+	mr.Set("test:baz", "qux")
+
+	values, err := store.MGet("foo", "baz")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "qux"}, values)
+
+	ttl, err := store.local.TTL("foo")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= 100*time.Millisecond, ttl)
+	ttl, err = store.local.TTL("baz")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 100*time.Millisecond && ttl <= time.Minute, ttl)
+
+	store.local.FlushDB()
+	v, err := store.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	ttl, err = store.local.TTL("foo")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= 100*time.Millisecond, ttl)
+}
+
+func TestRedisInvalidatorPublishTimeout(t *testing.T) {
+	// a server which accepts connections but never replies
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ri := NewRedisInvalidatorWithOptions("dat:invalidate", RedisOptions{
+		Addrs:          []string{ln.Addr().String()},
+		CommandTimeout: 50 * time.Millisecond,
+	})
+	start := time.Now()
+	assert.Error(t, ri.Publish("origin key foo"))
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nerdynz/dat/common"
+)
+
+// timeFormat is the DATETIME format written by go-sql-driver/mysql.
+const timeFormat = "2006-01-02 15:04:05.999999"
+
+// MySQL is the MySQL and MariaDB dialect.
+type MySQL struct{}
+
+// New returns a new MySQL dialect.
+func New() *MySQL {
+	return &MySQL{}
+}
+
+// WriteStringLiteral writes a string quoted with ', doubling any ' and
+// escaping backslashes and NUL. Strings are written as is when the server
+// runs with NO_BACKSLASH_ESCAPES, so interpolation must not be used then.
+func (md *MySQL) WriteStringLiteral(buf common.BufferWriter, val string) {
+	buf.WriteRune('\'')
+	if strings.ContainsAny(val, "'\\\x00") {
+		for _, char := range val {
+			switch char {
+			case '\'':
+				buf.WriteString(`''`)
+			case '\\':
+				buf.WriteString(`\\`)
+			case 0:
+				buf.WriteString(`\0`)
+			default:
+				buf.WriteRune(char)
+			}
+		}
+	} else {
+		buf.WriteString(val)
+	}
+	buf.WriteRune('\'')
+}
+
+// WriteIdentifier writes a column or table quoted with `. Anything other
+// than a plain identifier, optionally qualified as table.column, is written
+// as is.
+func (md *MySQL) WriteIdentifier(buf common.BufferWriter, ident string) {
+	common.WriteQuotedIdentifier(buf, ident, '`')
+}
+
+// WriteFormattedTime writes t in UTC as DATETIME, which has no time zone.
+// This matches go-sql-driver/mysql's default loc=UTC.
+func (md *MySQL) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
+	buf.WriteRune('\'')
+	buf.WriteString(t.UTC().Format(timeFormat))
+	buf.WriteRune('\'')
+}
+
+// WriteBool writes 1 or 0.
+func (md *MySQL) WriteBool(buf common.BufferWriter, b bool) {
+	if b {
+		buf.WriteRune('1')
+	} else {
+		buf.WriteRune('0')
+	}
+}
+
+// Rebind rewrites $1, $2 ... into ? placeholders. MySQL binds args in
+// order, so args are reordered and repeated to match the placeholders.
+// Backslash escapes within string literals are honoured.
+func (md *MySQL) Rebind(sql string, args []interface{}) (string, []interface{}, error) {
+	if !strings.Contains(sql, "$") {
+		return sql, args, nil
+	}
+
+	var bound []interface{}
+	var err error
+	sql = common.ReplacePlaceholdersBackslash(sql, func(buf common.BufferWriter, pos int) {
+		buf.WriteRune('?')
+		if pos < 1 || pos > len(args) {
+			if err == nil {
+				err = fmt.Errorf("placeholder $%d has no argument, %d given", pos, len(args))
+			}
+			return
+		}
+		bound = append(bound, args[pos-1])
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, bound, nil
+}
+
+// WriteLimitOffset writes LIMIT and OFFSET. MySQL requires a LIMIT before
+// OFFSET, the largest BIGINT UNSIGNED being no limit.
+func (md *MySQL) WriteLimitOffset(buf common.BufferWriter, limit uint64, hasLimit bool, offset uint64, hasOffset bool) {
+	if hasLimit {
+		buf.WriteString(" LIMIT ")
+		buf.WriteString(strconv.FormatUint(limit, 10))
+	} else if hasOffset {
+		buf.WriteString(" LIMIT 18446744073709551615")
+	}
+	if hasOffset {
+		buf.WriteString(" OFFSET ")
+		buf.WriteString(strconv.FormatUint(offset, 10))
+	}
+}
+
+// Supports returns true for ON DUPLICATE KEY UPDATE only.
+func (md *MySQL) Supports(feature common.Feature) bool {
+	return feature == common.FeatureOnDuplicateKeyUpdate
+}
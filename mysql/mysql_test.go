@@ -0,0 +1,149 @@
+package mysql_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"name":        "`name`",
+		"people.name": "`people`.`name`",
+		"count(*)":    "count(*)",
+		"people p":    "`people` `p`",
+		`"a""b"`:      "`a\"b`",
+		"a`b":         "a`b",
+	}
+	for ident, expected := range cases {
+		var buf bytes.Buffer
+		mysql.New().WriteIdentifier(&buf, ident)
+		assert.Equal(t, expected, buf.String(), ident)
+	}
+}
+
+func TestWriteLiterals(t *testing.T) {
+	var buf bytes.Buffer
+	d := mysql.New()
+	d.WriteStringLiteral(&buf, "it's a \\ \x00")
+	buf.WriteRune(' ')
+	d.WriteBool(&buf, true)
+	buf.WriteRune(' ')
+	d.WriteFormattedTime(&buf, time.Date(2018, 7, 1, 12, 30, 0, 500000, time.FixedZone("", 3600)))
+	assert.Equal(t, `'it''s a \\ \0' 1 '2018-07-01 11:30:00.0005'`, buf.String())
+}
+
+func TestRebind(t *testing.T) {
+	sql, args, err := mysql.New().Rebind("SELECT '$1' FROM t WHERE a = $2 AND b = $1 AND c = $2", []interface{}{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '$1' FROM t WHERE a = ? AND b = ? AND c = ?", sql)
+	assert.Equal(t, []interface{}{2, 1, 2}, args)
+
+	sql, args, err = mysql.New().Rebind("SELECT 'a\\'$1', b FROM t WHERE c = $1", []interface{}{7})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 'a\\'$1', b FROM t WHERE c = ?", sql)
+	assert.Equal(t, []interface{}{7}, args)
+
+	_, _, err = mysql.New().Rebind("SELECT $2", []interface{}{1})
+	assert.Error(t, err)
+}
+
+func TestSelectLimitOffset(t *testing.T) {
+	sql, args, err := dat.Select("id").From("people").Where("name = $1", "mario").Offset(10).
+		WithDialect(mysql.New()).
+		Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM `people` WHERE (name = ?) LIMIT 18446744073709551615 OFFSET 10", sql)
+	assert.Equal(t, []interface{}{"mario"}, args)
+}
+
+func TestUpsert(t *testing.T) {
+	sql, args, err := dat.Upsert("people").
+		Columns("name", "email").
+		Values("mario", "mario@example.com").
+		WithDialect(mysql.New()).
+		Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO `people` (`name`, `email`) VALUES (?,?) "+
+		"ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `email` = VALUES(`email`)", sql)
+	assert.Equal(t, []interface{}{"mario", "mario@example.com"}, args)
+}
+
+func TestReturningNotSupported(t *testing.T) {
+	d := mysql.New()
+	_, _, err := dat.InsertInto("people").Columns("name").Values("mario").Returning("id").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.Update("people").Set("name", "mario").Returning("id").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.Insect("people").Columns("name").Values("mario").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+}
+
+func TestNotSupported(t *testing.T) {
+	d := mysql.New()
+	_, _, err := dat.Upsert("people").Columns("name").Values("mario").Where("name = $1", "mario").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.Update("people").Set("name", "mario").Limit(1).Offset(1).WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.SelectDoc("name").From("people").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+
+	sql, _, err := dat.Update("people").Set("name", "mario").Limit(1).WithDialect(d).ToSQL()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE `people` SET `name` = ? LIMIT 1", sql)
+}
+
+func TestToSQL(t *testing.T) {
+	d := mysql.New()
+	cases := []struct {
+		builder dat.Builder
+		sql     string
+		args    []interface{}
+	}{
+		{
+			dat.Select("id", "name").From("people").Where("a = $2 AND b = $1", 1, 2).WithDialect(d),
+			"SELECT id, name FROM `people` WHERE (a = ? AND b = ?)",
+			[]interface{}{2, 1},
+		},
+		{
+			dat.InsertInto("people").Columns("name", "email").Values("mario", "mario@example.com").WithDialect(d),
+			"INSERT INTO `people` (`name`,`email`) VALUES (?,?)",
+			[]interface{}{"mario", "mario@example.com"},
+		},
+		{
+			dat.Update("people").Set("name", "x").Where("id = $1", 1).WithDialect(d),
+			"UPDATE `people` SET `name` = ? WHERE (id = ?)",
+			[]interface{}{"x", 1},
+		},
+		{
+			dat.DeleteFrom("people").Where("id = $1", 1).WithDialect(d),
+			"DELETE FROM `people` WHERE (id = ?)",
+			[]interface{}{1},
+		},
+		{
+			dat.Upsert("people").Columns("name").Values("mario").WithDialect(d),
+			"INSERT INTO `people` (`name`) VALUES (?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+			[]interface{}{"mario"},
+		},
+		{
+			dat.Call("hello", "mario", 1).WithDialect(d),
+			"SELECT * FROM hello(?,?)",
+			[]interface{}{"mario", 1},
+		},
+		{
+			dat.SQL("SELECT * FROM people WHERE a = $2 AND b = $1", 1, 2).WithDialect(d),
+			"SELECT * FROM people WHERE a = ? AND b = ?",
+			[]interface{}{2, 1},
+		},
+	}
+	for _, c := range cases {
+		sql, args, err := c.builder.ToSQL()
+		assert.NoError(t, err)
+		assert.Equal(t, c.sql, sql)
+		assert.Equal(t, c.args, args)
+	}
+}
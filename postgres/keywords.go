@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/nerdynz/dat/common"
+)
+
+// reservedWords are the keywords which cannot be used as column or table
+// names unless quoted, see
+// https://www.postgresql.org/docs/current/sql-keywords-appendix.html
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true,
+	"authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true,
+	"column": true, "concurrently": true, "constraint": true, "create": true,
+	"cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "freeze": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true,
+	"is": true, "isnull": true, "join": true, "lateral": true, "leading": true,
+	"left": true, "like": true, "limit": true, "localtime": true,
+	"localtimestamp": true, "natural": true, "not": true, "notnull": true,
+	"null": true, "offset": true, "on": true, "only": true, "or": true,
+	"order": true, "outer": true, "overlaps": true, "placing": true,
+	"primary": true, "references": true, "returning": true, "right": true,
+	"select": true, "session_user": true, "similar": true, "some": true,
+	"symmetric": true, "table": true, "tablesample": true, "then": true,
+	"to": true, "trailing": true, "true": true, "union": true, "unique": true,
+	"user": true, "using": true, "variadic": true, "verbose": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// isReservedWord returns true if part must be quoted to be an identifier,
+// whatever its case.
+func isReservedWord(part string) bool {
+	return reservedWords[strings.ToLower(part)]
+}
+
+// foldReservedWords lowers the unquoted parts of id which are reserved words.
+// Postgres folds unquoted identifiers to lower case, so USER is written
+// quoted as "user" and not "USER".
+func foldReservedWords(id *common.Identifier) {
+	for i := range id.Parts {
+		foldReservedWord(&id.Parts[i])
+	}
+	if id.Alias != nil {
+		foldReservedWord(id.Alias)
+	}
+}
+
+func foldReservedWord(part *common.IdentifierPart) {
+	if !part.Quoted && isReservedWord(part.Name) {
+		part.Name = strings.ToLower(part.Name)
+	}
+}
@@ -91,13 +91,50 @@ func (pd *Postgres) WriteStringLiteral(buf common.BufferWriter, val string) {
 	}
 }
 
-// WriteIdentifier writes escaped identifier.
+// WriteIdentifier writes an identifier such as `table.column` or
+// `schema.table alias`, quoting reserved words and parts which were quoted.
+// Other parts are not quoted since Postgres folds unquoted identifiers to
+// lower case. Anything other than an identifier, such as an expression, is
+// written as is.
 func (pd *Postgres) WriteIdentifier(buf common.BufferWriter, ident string) {
-	// problem: dat is a loose SQL builder. `ident` could have
-	// value like "public.table tbl". It would be time consuming, not to
-	// mention, error prone to parse an identifier and quote it properly.
-	// Not worth it. The user must quote strings properly.
-	buf.WriteString(ident)
+	id, ok := common.ParseIdentifier(ident)
+	if !ok {
+		buf.WriteString(ident)
+		return
+	}
+	foldReservedWords(id)
+	id.Write(buf, '"', isReservedWord)
+}
+
+// WriteBool writes 't' or 'f'.
+func (pd *Postgres) WriteBool(buf common.BufferWriter, b bool) {
+	if b {
+		buf.WriteString(`'t'`)
+	} else {
+		buf.WriteString(`'f'`)
+	}
+}
+
+// Rebind returns sql and args as is, dat builds Postgres placeholders.
+func (pd *Postgres) Rebind(sql string, args []interface{}) (string, []interface{}, error) {
+	return sql, args, nil
+}
+
+// WriteLimitOffset writes LIMIT and OFFSET.
+func (pd *Postgres) WriteLimitOffset(buf common.BufferWriter, limit uint64, hasLimit bool, offset uint64, hasOffset bool) {
+	if hasLimit {
+		buf.WriteString(" LIMIT ")
+		buf.WriteString(strconv.FormatUint(limit, 10))
+	}
+	if hasOffset {
+		buf.WriteString(" OFFSET ")
+		buf.WriteString(strconv.FormatUint(offset, 10))
+	}
+}
+
+// Supports returns true for all features but ON DUPLICATE KEY UPDATE.
+func (pd *Postgres) Supports(feature common.Feature) bool {
+	return feature != common.FeatureOnDuplicateKeyUpdate
 }
 
 // WriteFormattedTime formats t into a format postgres understands.
@@ -0,0 +1,32 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nerdynz/dat/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"name":             "name",
+		"userName":         "userName",
+		"user":             `"user"`,
+		"USER":             `"user"`,
+		"Current_User":     `"current_user"`,
+		"public.user u":    `public."user" u`,
+		"people AS order":  `people "order"`,
+		"people AS Order":  `people "order"`,
+		`"Mixed"."a""b"`:   `"Mixed"."a""b"`,
+		`"USER"`:           `"USER"`,
+		"people.*":         "people.*",
+		"count(*)":         "count(*)",
+		"lower(name) name": "lower(name) name",
+	}
+	for ident, expected := range cases {
+		var buf bytes.Buffer
+		postgres.New().WriteIdentifier(&buf, ident)
+		assert.Equal(t, expected, buf.String(), ident)
+	}
+}
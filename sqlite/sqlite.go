@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nerdynz/dat/common"
+)
+
+// timeFormat is the first format of go-sqlite3's SQLiteTimestampFormats, so
+// interpolated times scan back into time.Time.
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// SQLite is the SQLite dialect. RETURNING requires SQLite 3.35+.
+type SQLite struct{}
+
+// New returns a new SQLite dialect.
+func New() *SQLite {
+	return &SQLite{}
+}
+
+// WriteStringLiteral writes a string quoted with ', doubling any '.
+func (sd *SQLite) WriteStringLiteral(buf common.BufferWriter, val string) {
+	buf.WriteRune('\'')
+	if strings.Contains(val, "'") {
+		buf.WriteString(strings.Replace(val, "'", "''", -1))
+	} else {
+		buf.WriteString(val)
+	}
+	buf.WriteRune('\'')
+}
+
+// WriteIdentifier writes a column or table quoted with ". Anything other
+// than a plain identifier, optionally qualified as table.column, is written
+// as is.
+func (sd *SQLite) WriteIdentifier(buf common.BufferWriter, ident string) {
+	common.WriteQuotedIdentifier(buf, ident, '"')
+}
+
+// WriteFormattedTime writes t as a text timestamp.
+func (sd *SQLite) WriteFormattedTime(buf common.BufferWriter, t time.Time) {
+	buf.WriteRune('\'')
+	buf.WriteString(t.Format(timeFormat))
+	buf.WriteRune('\'')
+}
+
+// WriteBool writes 1 or 0, SQLite has no boolean type.
+func (sd *SQLite) WriteBool(buf common.BufferWriter, b bool) {
+	if b {
+		buf.WriteRune('1')
+	} else {
+		buf.WriteRune('0')
+	}
+}
+
+// Rebind rewrites $1, $2 ... into ?1, ?2 ... which bind the same args.
+func (sd *SQLite) Rebind(sql string, args []interface{}) (string, []interface{}, error) {
+	if !strings.Contains(sql, "$") {
+		return sql, args, nil
+	}
+	sql = common.ReplacePlaceholders(sql, func(buf common.BufferWriter, pos int) {
+		buf.WriteRune('?')
+		buf.WriteString(strconv.Itoa(pos))
+	})
+	return sql, args, nil
+}
+
+// WriteLimitOffset writes LIMIT and OFFSET. SQLite requires a LIMIT before
+// OFFSET, -1 being no limit.
+func (sd *SQLite) WriteLimitOffset(buf common.BufferWriter, limit uint64, hasLimit bool, offset uint64, hasOffset bool) {
+	if hasLimit {
+		buf.WriteString(" LIMIT ")
+		buf.WriteString(strconv.FormatUint(limit, 10))
+	} else if hasOffset {
+		buf.WriteString(" LIMIT -1")
+	}
+	if hasOffset {
+		buf.WriteString(" OFFSET ")
+		buf.WriteString(strconv.FormatUint(offset, 10))
+	}
+}
+
+// Supports returns true for RETURNING only.
+func (sd *SQLite) Supports(feature common.Feature) bool {
+	return feature == common.FeatureReturning
+}
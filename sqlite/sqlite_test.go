@@ -0,0 +1,289 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/postgres"
+	"github.com/nerdynz/dat/sqlite"
+	runner "github.com/nerdynz/dat/sqlx-runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"name":          `"name"`,
+		"people.name":   `"people"."name"`,
+		"count(*)":      "count(*)",
+		"people p":      `"people" "p"`,
+		`"quoted"`:      `"quoted"`,
+		"public.people": `"public"."people"`,
+		"people.*":      `"people".*`,
+	}
+	for ident, expected := range cases {
+		var buf bytes.Buffer
+		sqlite.New().WriteIdentifier(&buf, ident)
+		assert.Equal(t, expected, buf.String(), ident)
+	}
+}
+
+func TestWriteLiterals(t *testing.T) {
+	var buf bytes.Buffer
+	d := sqlite.New()
+	d.WriteStringLiteral(&buf, "it's")
+	buf.WriteRune(' ')
+	d.WriteBool(&buf, true)
+	buf.WriteRune(' ')
+	d.WriteBool(&buf, false)
+	buf.WriteRune(' ')
+	d.WriteFormattedTime(&buf, time.Date(2018, 7, 1, 12, 30, 0, 500, time.UTC))
+	assert.Equal(t, `'it''s' 1 0 '2018-07-01 12:30:00.0000005+00:00'`, buf.String())
+}
+
+func TestRebind(t *testing.T) {
+	sql, args, err := sqlite.New().Rebind(
+		"SELECT '$1', \"$2\" FROM t WHERE a = $1 AND b = $2 -- $3\nAND c = $1",
+		[]interface{}{1, 2},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '$1', \"$2\" FROM t WHERE a = ?1 AND b = ?2 -- $3\nAND c = ?1", sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+}
+
+func TestToSQL(t *testing.T) {
+	d := sqlite.New()
+	cases := []struct {
+		builder dat.Builder
+		sql     string
+		args    []interface{}
+	}{
+		{
+			dat.Select("id", "name").From("people").Where("a = $2 AND b = $1", 1, 2).WithDialect(d),
+			"SELECT id, name FROM \"people\" WHERE (a = ?2 AND b = ?1)",
+			[]interface{}{1, 2},
+		},
+		{
+			dat.InsertInto("people").Columns("name", "email").Values("mario", "mario@example.com").Returning("id").WithDialect(d),
+			`INSERT INTO "people" ("name","email") VALUES (?1,?2) RETURNING "id"`,
+			[]interface{}{"mario", "mario@example.com"},
+		},
+		{
+			dat.Update("people").Set("name", "x").Where("id = $1", 1).WithDialect(d),
+			`UPDATE "people" SET "name" = ?1 WHERE (id = ?2)`,
+			[]interface{}{"x", 1},
+		},
+		{
+			dat.DeleteFrom("people").Where("id = $1", 1).WithDialect(d),
+			"DELETE FROM \"people\" WHERE (id = ?1)",
+			[]interface{}{1},
+		},
+		{
+			dat.Call("hello", "mario", 1).WithDialect(d),
+			"SELECT * FROM hello(?1,?2)",
+			[]interface{}{"mario", 1},
+		},
+		{
+			dat.SQL("SELECT * FROM people WHERE a = $2 AND b = $1", 1, 2).WithDialect(d),
+			"SELECT * FROM people WHERE a = ?2 AND b = ?1",
+			[]interface{}{1, 2},
+		},
+	}
+	for _, c := range cases {
+		sql, args, err := c.builder.ToSQL()
+		assert.NoError(t, err)
+		assert.Equal(t, c.sql, sql)
+		assert.Equal(t, c.args, args)
+	}
+
+	_, _, err := dat.Upsert("people").Columns("name").Values("mario").Where("name = $1", "mario").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.Insect("people").Columns("name").Values("mario").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+	_, _, err = dat.SelectDoc("name").From("people").WithDialect(d).ToSQL()
+	assert.Error(t, err)
+}
+
+func openDB(t *testing.T) *runner.DB {
+	db, err := runner.Open("sqlite3", ":memory:", runner.OpenOptions{
+		// each connection has its own in-memory database
+		Pool: runner.PoolOptions{MaxOpenConns: 1},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = db.Exec(`
+		CREATE TABLE people (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			email TEXT,
+			active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME
+		)
+	`)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return db
+}
+
+type person struct {
+	ID        int64          `db:"id"`
+	Name      string         `db:"name"`
+	Email     dat.NullString `db:"email"`
+	Active    bool           `db:"active"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func testRunner(t *testing.T, interpolate bool) {
+	defer func(enabled bool) { dat.EnableInterpolation = enabled }(dat.EnableInterpolation)
+	dat.EnableInterpolation = interpolate
+
+	db := openDB(t)
+	defer db.DB.Close()
+
+	created := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+	res, err := db.
+		InsertInto("people").
+		Columns("name", "email", "active", "created_at").
+		Values("Mario", "mario@example.com", true, created).
+		Values("Peach", nil, false, created).
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, res.RowsAffected)
+
+	var people []*person
+	err = db.
+		Select("id", "name", "email", "active", "created_at").
+		From("people").
+		Where("name = $1 OR active = $2", "Mario", false).
+		OrderBy("id").
+		QueryStructs(&people)
+	assert.NoError(t, err)
+	if assert.Len(t, people, 2) {
+		assert.Equal(t, "Mario", people[0].Name)
+		assert.Equal(t, "mario@example.com", people[0].Email.String)
+		assert.True(t, people[0].Active)
+		assert.True(t, created.Equal(people[0].CreatedAt))
+		assert.False(t, people[1].Email.Valid)
+		assert.False(t, people[1].Active)
+	}
+
+	res, err = db.
+		Update("people").
+		Set("email", "peach@example.com").
+		Where("name = $1", "Peach").
+		Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	var email string
+	err = db.SQL("SELECT email FROM people WHERE name = $1", "Peach").QueryScalar(&email)
+	assert.NoError(t, err)
+	assert.Equal(t, "peach@example.com", email)
+
+	res, err = db.DeleteFrom("people").Where(dat.Eq{"active": false}).Exec()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.RowsAffected)
+
+	var count int
+	err = db.SQL("SELECT count(*) FROM people").QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestRunner(t *testing.T) {
+	testRunner(t, false)
+}
+
+func TestRunnerInterpolated(t *testing.T) {
+	testRunner(t, true)
+}
+
+func TestDialectPerDB(t *testing.T) {
+	db := openDB(t)
+	defer db.DB.Close()
+
+	// opening a SQLite DB leaves the default dialect as is
+	assert.IsType(t, &postgres.Postgres{}, dat.Dialect)
+	assert.IsType(t, &sqlite.SQLite{}, db.Dialect())
+
+	sql, _, err := db.Select("id").From("people").Where(dat.Eq{"name": "Mario"}).Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM "people" WHERE ("name" = ?1)`, sql)
+
+	sql, _, err = dat.Select("id").From("people").Where(dat.Eq{"name": "Mario"}).Interpolate()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM people WHERE (name = $1)`, sql)
+}
+
+func TestRunnerTx(t *testing.T) {
+	db := openDB(t)
+	defer db.DB.Close()
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO people (name) VALUES ($1)", "Luigi")
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+
+	var count int
+	err = db.SQL("SELECT count(*) FROM people").QueryScalar(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestNotSupported(t *testing.T) {
+	db := openDB(t)
+	defer db.DB.Close()
+
+	_, err := db.Select("id").From("people").QueryJSON()
+	assert.Error(t, err)
+	var dest []map[string]interface{}
+	err = db.Select("id").From("people").QueryObject(&dest)
+	assert.Error(t, err)
+	_, err = db.SelectDoc("id").From("people").QueryJSON()
+	assert.Error(t, err)
+
+	var count int
+	err = db.SQL("SELECT count(*) FROM people").Timeout(time.Second).QueryScalar(&count)
+	assert.Error(t, err)
+
+	_, err = db.BeginWith(runner.TxOptions{ReadOnly: true, Deferrable: true})
+	assert.Error(t, err)
+
+	m := db.NewMigrator()
+	_, err = m.Up()
+	assert.Error(t, err)
+	_, err = m.Status()
+	assert.Error(t, err)
+}
+
+func TestQueryxInFlight(t *testing.T) {
+	db := openDB(t)
+	defer db.DB.Close()
+	_, err := db.InsertInto("people").Columns("name").Values("Mario").Values("Peach").Exec()
+	assert.NoError(t, err)
+
+	rows, err := db.Select("name").From("people").Execer.(*runner.Execer).Queryx()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, db.Stats().InFlight)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	assert.Equal(t, 2, n)
+	// read rows are no longer in flight, even before Close
+	assert.EqualValues(t, 0, db.Stats().InFlight)
+	assert.NoError(t, rows.Close())
+	assert.EqualValues(t, 0, db.Stats().InFlight)
+
+	rows, err = db.Select("name").From("people").Execer.(*runner.Execer).Queryx()
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	assert.EqualValues(t, 1, db.Stats().InFlight)
+	assert.NoError(t, rows.Close())
+	assert.EqualValues(t, 0, db.Stats().InFlight)
+}
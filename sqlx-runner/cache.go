@@ -0,0 +1,44 @@
+package runner
+
+import "github.com/nerdynz/dat/kvs"
+
+// CacheOptions configures the cache of a DB.
+type CacheOptions struct {
+	// Prefix is prepended to every key read or written through the DB. Use
+	// it to keep the keys of several databases apart in a shared store.
+	Prefix string
+}
+
+// queryCache is the cache configuration shared by a DB and the
+// transactions begun from it.
+type queryCache struct {
+	store  kvs.KeyValueStore
+	prefix string
+}
+
+// SetCache sets the cache used by queries on this DB and by any Tx begun
+// from it afterwards. A nil store reverts to the package level Cache.
+func (db *DB) SetCache(store kvs.KeyValueStore, opts CacheOptions) {
+	if store == nil {
+		db.cache = nil
+		return
+	}
+	db.cache = &queryCache{store: store, prefix: opts.Prefix}
+}
+
+// CacheStore returns the store used by this DB, which is the package level
+// Cache unless one was set through SetCache.
+func (db *DB) CacheStore() kvs.KeyValueStore {
+	if db.cache != nil {
+		return db.cache.store
+	}
+	return Cache
+}
+
+// cacheStore returns the store and key prefix for this execer's query.
+func (ex *Execer) cacheStore() (kvs.KeyValueStore, string) {
+	if ex.cache != nil {
+		return ex.cache.store, ex.cache.prefix
+	}
+	return Cache, ""
+}
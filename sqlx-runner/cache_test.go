@@ -7,6 +7,7 @@ import (
 
 	"github.com/mgutz/jo/v1"
 	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/kvs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -260,3 +261,34 @@ func TestCacheSelectQuerySliceByHash(t *testing.T) {
 		assert.Equal(t, ids, []int64{1})
 	}
 }
+
+func TestCachePerDB(t *testing.T) {
+	Cache.FlushDB()
+	store := kvs.NewMemoryKeyValueStore(1 * time.Second)
+	db := NewDBFromSqlx(testDB.DB)
+	db.SetCache(store, CacheOptions{Prefix: "tenant1:"})
+
+	var name string
+	err := db.
+		Select("name").
+		From("people").
+		Where("email = 'john@acme.com'").
+		Cache("selectdoc.12", 1*time.Second, false).
+		QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "John", name)
+
+	v, err := store.Get("tenant1:selectdoc.12")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, v)
+
+	// the package level cache is untouched
+	v, _ = Cache.Get("selectdoc.12")
+	assert.Empty(t, v)
+
+	// transactions inherit the cache of their DB
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	assert.Equal(t, db.cache, tx.cache)
+}
@@ -1,12 +1,18 @@
 package runner
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/nerdynz/dat/dat"
-	"github.com/nerdynz/dat/internal/log"
 	"github.com/jmoiron/sqlx"
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/mysql"
+	"github.com/nerdynz/dat/postgres"
+	"github.com/nerdynz/dat/sqlite"
 )
 
 // DB represents an abstract database connection pool.
@@ -14,17 +20,20 @@ type DB struct {
 	DB *sqlx.DB
 	*Queryable
 	Version int64
+
+	healthMu sync.Mutex
+	health   *healthCheck
 }
 
 var standardConformingStrings string
 
-// pgMustNotAllowEscapeSequence checks if Postgres treats backlashes
+// pgCheckEscapeSequence checks if Postgres treats backlashes
 // literally in strings when dat.EnableInterpolation == true. If escape
 // sequences are allowed, then it is unsafe to use interpolation and
-// this function panics.
-func pgMustNotAllowEscapeSequence(conn *DB) {
+// this function returns an error.
+func pgCheckEscapeSequence(conn *DB) error {
 	if !dat.EnableInterpolation {
-		return
+		return nil
 	}
 
 	if standardConformingStrings == "" {
@@ -32,62 +41,172 @@ func pgMustNotAllowEscapeSequence(conn *DB) {
 			SQL("select setting from pg_settings where name='standard_conforming_strings'").
 			QueryScalar(&standardConformingStrings)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
 
 	if standardConformingStrings != "on" {
-		log.Fatal(fmt.Sprintf("Database allows escape sequences. Cannot be used with interpolation. "+
+		return fmt.Errorf("Database allows escape sequences. Cannot be used with interpolation. "+
 			"standard_conforming_strings=%q\n"+
 			"See http://www.postgresql.org/docs/9.3/interactive/sql-syntax-lexical.html#SQL-SYNTAX-STRINGS-ESCAPE",
-			standardConformingStrings))
+			standardConformingStrings)
+	}
+	return nil
+}
+
+// mysqlCheckEscapeSequence checks if MySQL treats backslashes as escape
+// characters when dat.EnableInterpolation == true. The MySQL dialect escapes
+// backslashes, which would be stored twice with NO_BACKSLASH_ESCAPES.
+func mysqlCheckEscapeSequence(conn *DB) error {
+	if !dat.EnableInterpolation {
+		return nil
 	}
+
+	var sqlMode string
+	if err := conn.SQL("SELECT @@SESSION.sql_mode").QueryScalar(&sqlMode); err != nil {
+		return err
+	}
+	if strings.Contains(sqlMode, "NO_BACKSLASH_ESCAPES") {
+		return fmt.Errorf("Database does not allow escape sequences. Cannot be used with interpolation. "+
+			"sql_mode=%q", sqlMode)
+	}
+	return nil
 }
 
-func pgSetVersion(db *DB) {
+func pgSetVersion(db *DB) error {
 	err := db.
 		SQL("SHOW server_version_num").
 		QueryScalar(&db.Version)
 	if err != nil {
-		log.Fatal("Could not query Postgres version")
-		return
+		return fmt.Errorf("Could not query Postgres version: %w", err)
 	}
+	return nil
 }
 
-// NewDB instantiates a Connection for a given database/sql connection
-func NewDB(db *sql.DB, driverName string) *DB {
-	database := sqlx.NewDb(db, driverName)
-	conn := &DB{DB: database, Queryable: &Queryable{database}}
-	if driverName == "postgres" {
-		pgMustNotAllowEscapeSequence(conn)
-		pgSetVersion(conn)
-		if dat.Strict {
-			conn.SQL("SET client_min_messages to 'DEBUG';")
-		}
+// OpenOptions are the options for Open.
+type OpenOptions struct {
+	// PingTimeout bounds the ping made before Open returns. 0 means no limit.
+	PingTimeout time.Duration
+
+	// PingRetry retries the ping with exponential backoff for up to
+	// PingRetry, for databases which may still be starting. 0 pings once.
+	PingRetry time.Duration
+
+	// Pool configures the connection pool.
+	Pool PoolOptions
+}
+
+// Open opens and pings a database, then instantiates a DB for it.
+func Open(driver string, dsn string, opts OpenOptions) (*DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Pool.apply(db)
+
+	if opts.PingRetry > 0 {
+		err = Ping(db, opts.PingRetry)
 	} else {
-		panic("Unsupported driver: " + driverName)
+		ctx := context.Background()
+		if opts.PingTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.PingTimeout)
+			defer cancel()
+		}
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Could not ping database: %w", err)
+	}
+
+	conn, err := OpenDB(db, driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialectFor returns the dialect of a database/sql driver.
+func dialectFor(driverName string) (dat.SQLDialect, error) {
+	switch driverName {
+	case "postgres", "pgx":
+		return postgres.New(), nil
+	case "mysql":
+		return mysql.New(), nil
+	case "sqlite3", "sqlite":
+		return sqlite.New(), nil
+	}
+	return nil, fmt.Errorf("Unsupported driver: %s", driverName)
+}
+
+// OpenDB instantiates a DB for a given database/sql connection. The driver
+// name selects the dialect: "postgres", "mysql" or "sqlite3".
+func OpenDB(db *sql.DB, driverName string) (*DB, error) {
+	return OpenSqlx(sqlx.NewDb(db, driverName))
+}
+
+// OpenSqlx instantiates a DB from an existing sqlx.DB.
+func OpenSqlx(dbx *sqlx.DB) (*DB, error) {
+	dialect, err := dialectFor(dbx.DriverName())
+	if err != nil {
+		return nil, err
+	}
+	conn := newDB(dbx, dialect)
+	if _, ok := dialect.(*mysql.MySQL); ok {
+		if err := mysqlCheckEscapeSequence(conn); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	if _, ok := dialect.(*postgres.Postgres); !ok {
+		return conn, nil
+	}
+	if err := pgCheckEscapeSequence(conn); err != nil {
+		return nil, err
+	}
+	if err := pgSetVersion(conn); err != nil {
+		return nil, err
+	}
+	if dat.Strict {
+		conn.SQL("SET client_min_messages to 'DEBUG';")
+	}
+	return conn, nil
+}
+
+// newDB creates a DB without connecting to the database.
+func newDB(dbx *sqlx.DB, dialect dat.SQLDialect) *DB {
+	return &DB{DB: dbx, Queryable: &Queryable{runner: dbx, counters: &dbCounters{}, dialect: dialect}}
+}
+
+// NewDB instantiates a Connection for a given database/sql connection.
+// It panics on error, see OpenDB.
+func NewDB(db *sql.DB, driverName string) *DB {
+	conn, err := OpenDB(db, driverName)
+	if err != nil {
+		panic(err)
 	}
 	return conn
 }
 
 // NewDBFromString instantiates a Connection from a given driver
-// and connection string.
+// and connection string. It panics on error, see Open.
 func NewDBFromString(driver string, connectionString string) *DB {
-	db, err := sql.Open(driver, connectionString)
-	if err != nil {
-		log.Fatal("Database error ", "err", err)
-	}
-	err = db.Ping()
+	conn, err := Open(driver, connectionString, OpenOptions{})
 	if err != nil {
-		log.Fatal("Could not ping database", "err", err)
+		panic(err)
 	}
-	return NewDB(db, driver)
+	return conn
 }
 
 // NewDBFromSqlx creates a new Connection object from existing Sqlx.DB.
+// It panics on error, see OpenSqlx.
 func NewDBFromSqlx(dbx *sqlx.DB) *DB {
-	conn := &DB{DB: dbx, Queryable: &Queryable{dbx}}
-	pgMustNotAllowEscapeSequence(conn)
-	pgSetVersion(conn)
+	conn, err := OpenSqlx(dbx)
+	if err != nil {
+		panic(err)
+	}
 	return conn
 }
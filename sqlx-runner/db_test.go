@@ -1,7 +1,9 @@
 package runner
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,3 +12,25 @@ func TestVersion(t *testing.T) {
 	// require at least 9.3+ for testing
 	assert.True(t, testDB.Version > 90300)
 }
+
+func TestOpen(t *testing.T) {
+	db, err := Open(os.Getenv("DAT_DRIVER"), os.Getenv("DAT_DSN"), OpenOptions{PingTimeout: 5 * time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, testDB.Version, db.Version)
+	db.DB.Close()
+
+	_, err = Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1", OpenOptions{})
+	assert.Error(t, err)
+}
+
+func TestOpenDBUnsupportedDriver(t *testing.T) {
+	_, err := OpenDB(sqlDB, "oracle")
+	assert.Error(t, err)
+	assert.Panics(t, func() { NewDB(sqlDB, "oracle") })
+}
+
+func TestCreateMetaTable(t *testing.T) {
+	assert.NoError(t, testDB.CreateMetaTable())
+	// idempotent
+	assert.NoError(t, testDB.CreateMetaTable())
+}
@@ -9,10 +9,12 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 	"github.com/nerdynz/dat/kvs"
@@ -45,16 +47,21 @@ func toOutputStr(args []interface{}) string {
 			buf.WriteString(fmt.Sprintf("%v", t))
 		case []byte:
 			buf.WriteString("<binary>")
+		case dat.SecretValue:
+			buf.WriteString(dat.Redacted)
 		}
 	}
 	return buf.String()
 }
 
-func logSQLError(err error, msg string, statement string, args []interface{}) error {
+// logSQLError logs err and returns it as a dat.QueryError. sql.ErrNoRows
+// is returned as is unless dat.Strict is set, and the cancellation of a
+// query which timed out is returned as dat.ErrTimedout.
+func logSQLError(ql *queryLogger, err error, msg string, kind string, start time.Time, statement string, args []interface{}) error {
 	// it might be possible for a query to finish in between ex.timeout expiring locally
 	// and before pg_cancel_backend executes on postgres server.
 	if pe, ok := err.(*pq.Error); ok {
-		if pe.Code == "57014" {
+		if pe.Code == dat.SQLStateQueryCanceled {
 			// dat initiates the cancellation of a query on timeout.  Coerce the error into
 			// a timedout error so the end user does not see a false error in the logs.
 			if strings.HasPrefix(statement, queryIDPrefix) {
@@ -62,16 +69,92 @@ func logSQLError(err error, msg string, statement string, args []interface{}) er
 			}
 		}
 	} else if err == sql.ErrNoRows {
-		if dat.Strict {
-			return log.ErrorE(msg, "err", err, "sql", statement, "args", toOutputStr(args))
+		if !dat.Strict {
+			// not finding a row is an expected outcome, not a failure of
+			// the statement, so it is logged like any query returning no rows
+			ql.executed(kind, start, statement, args, 0)
+			return err
 		}
-		if log.HasDebugLogger() {
-			log.Debug(msg, "err", err, "sql", statement, "args", toOutputStr(args))
+	}
+
+	ql.failed(kind, msg, start, statement, args, err)
+	return &dat.QueryError{
+		SQL:     statement,
+		Args:    redactArgs(args),
+		Builder: kind,
+		Elapsed: time.Since(start),
+		Err:     dat.WrapSQLError(msg, err),
+	}
+}
+
+// executed logs a statement of the builder which succeeded and records it
+// in the statistics. rows is the number of rows returned or affected, -1 if
+// unknown.
+func (ex *Execer) executed(st *statement, start time.Time, rows int64) {
+	elapsed := time.Since(start)
+	ex.logger.executed(ex.kind(), start, st.logSQL, st.logArgs, rows)
+	ex.stats.record(st.fingerprint, elapsed, rows, false)
+	ex.logSlowPlan(st, elapsed)
+}
+
+// sqlError logs a statement of the builder which failed with err, records
+// it in the statistics and returns the error to hand to the caller. See
+// logSQLError.
+func (ex *Execer) sqlError(st *statement, start time.Time, err error, msg string) error {
+	err = logSQLError(ex.logger, err, msg, ex.kind(), start, st.logSQL, st.logArgs)
+	if err == sql.ErrNoRows {
+		ex.stats.record(st.fingerprint, time.Since(start), 0, false)
+	} else {
+		ex.stats.record(st.fingerprint, time.Since(start), -1, true)
+	}
+	return err
+}
+
+// rowsAffected returns the rows affected by result, -1 if unknown.
+func rowsAffected(result sql.Result) int64 {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// redactArgs returns a copy of args safe to hand to callers and error
+// reporters. Secrets and binary values are replaced.
+func redactArgs(args []interface{}) []interface{} {
+	if args == nil {
+		return nil
+	}
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case []byte, dat.JSON:
+			redacted[i] = "<binary>"
+		case dat.SecretValue:
+			redacted[i] = dat.Redacted
+		default:
+			redacted[i] = arg
 		}
-		return err
 	}
+	return redacted
+}
+
+// builderKind is the kind of builder b, such as "Select" for a
+// *dat.SelectBuilder.
+func builderKind(b dat.Builder) string {
+	if b == nil {
+		return ""
+	}
+	t := reflect.TypeOf(b)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Builder")
+}
 
-	return log.ErrorE(msg, "err", err, "sql", statement, "args", toOutputStr(args))
+// kind is the kind of builder executed.
+func (ex *Execer) kind() string {
+	return builderKind(ex.builder)
 }
 
 func logExecutionTime(start time.Time, sql string, args []interface{}) {
@@ -95,6 +178,7 @@ func logExecutionTime(start time.Time, sql string, args []interface{}) {
 }
 
 func (ex *Execer) exec() (sql.Result, error) {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.execFn()
 	}
@@ -119,43 +203,73 @@ func (ex *Execer) exec() (sql.Result, error) {
 // execFn executes the query built by builder. Use execFn when data is not
 // to be returned.
 func (ex *Execer) execFn() (sql.Result, error) {
-	fullSQL, args, err := ex.Interpolate()
+	st, err := ex.statement()
 	if err != nil {
-		return nil, log.ErrorE("execFn.10", "err", err, "sql", fullSQL)
+		return nil, log.ErrorE("execFn.10", "err", err)
 	}
-	defer logExecutionTime(time.Now(), fullSQL, args)
+	defer ex.counters.begin()()
+	start := time.Now()
 
 	var result sql.Result
-	result, err = ex.database.Exec(fullSQL, args...)
+	result, err = ex.database.Exec(st.sql, st.args...)
 	if err != nil {
-		return nil, logSQLError(err, "execFn.30:"+fmt.Sprintf("%T", err), fullSQL, args)
+		return nil, ex.sqlError(st, start, err, "execFn.30:"+fmt.Sprintf("%T", err))
 	}
 
+	ex.executed(st, start, rowsAffected(result))
 	return result, nil
 }
 
 // execSQL executes SQL. DO NOT add timeout logic here since this is called
 // by Cancel when a timeout occurs.
 func (ex *Execer) execSQL(fullSQL string, args []interface{}) (sql.Result, error) {
-	defer logExecutionTime(time.Now(), fullSQL, args)
+	defer ex.counters.begin()()
+	start := time.Now()
 
 	var result sql.Result
 	var err error
 	result, err = ex.database.Exec(fullSQL, args...)
 	if err != nil {
-		return nil, logSQLError(err, "execSQL.30", fullSQL, args)
+		return nil, logSQLError(ex.logger, err, "execSQL.30", ex.kind(), start, fullSQL, args)
 	}
 
+	ex.logger.executed(ex.kind(), start, fullSQL, args, rowsAffected(result))
 	return result, nil
 }
 
-func (ex *Execer) query() (*sqlx.Rows, error) {
+// Rows are the rows returned by Queryx. The statement is counted as in
+// flight until the rows are closed or fully read.
+type Rows struct {
+	*sqlx.Rows
+	done func()
+	once sync.Once
+}
+
+// Next prepares the next row, see sql.Rows.Next.
+func (rows *Rows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+	// database/sql closes the rows once they are read
+	rows.once.Do(rows.done)
+	return false
+}
+
+// Close closes the rows, see sql.Rows.Close.
+func (rows *Rows) Close() error {
+	err := rows.Rows.Close()
+	rows.once.Do(rows.done)
+	return err
+}
+
+func (ex *Execer) query() (*Rows, error) {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryFn()
 	}
 
 	ch := make(chan bool, 1)
-	var rows *sqlx.Rows
+	var rows *Rows
 	var err error
 	go func() {
 		rows, err = ex.queryFn()
@@ -172,22 +286,26 @@ func (ex *Execer) query() (*sqlx.Rows, error) {
 }
 
 // Query delegates to the internal runner's Query.
-func (ex *Execer) queryFn() (*sqlx.Rows, error) {
-	fullSQL, args, err := ex.Interpolate()
+func (ex *Execer) queryFn() (*Rows, error) {
+	st, err := ex.statement()
 	if err != nil {
 		return nil, err
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	done := ex.counters.begin()
+	start := time.Now()
+	rows, err := ex.database.Queryx(st.sql, st.args...)
 	if err != nil {
-		return nil, logSQLError(err, "queryFn.30", fullSQL, args)
+		done()
+		return nil, ex.sqlError(st, start, err, "queryFn.30")
 	}
 
-	return rows, nil
+	ex.executed(st, start, -1)
+	return &Rows{Rows: rows, done: done}, nil
 }
 
 func (ex *Execer) queryScalar(destinations ...interface{}) error {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryScalarFn(destinations)
 	}
@@ -213,7 +331,7 @@ func (ex *Execer) queryScalar(destinations ...interface{}) error {
 //
 // Returns sql.ErrNoRows if no value was found, and it was therefore not set.
 func (ex *Execer) queryScalarFn(destinations []interface{}) error {
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return err
 	}
@@ -226,31 +344,35 @@ func (ex *Execer) queryScalarFn(destinations []interface{}) error {
 		log.Error("queryScalarFn.10: Could not unmarshal cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
+	defer ex.counters.begin()()
+	start := time.Now()
 	// Run the query:
 	var rows *sqlx.Rows
-	rows, err = ex.database.Queryx(fullSQL, args...)
+	rows, err = ex.database.Queryx(st.sql, st.args...)
 	if err != nil {
-		return logSQLError(err, "queryScalarFn.12: querying database", fullSQL, args)
+		return ex.sqlError(st, start, err, "queryScalarFn.12: querying database")
 	}
 
 	defer rows.Close()
 	if rows.Next() {
 		err = rows.Scan(destinations...)
 		if err != nil {
-			return logSQLError(err, "queryScalarFn.14: scanning to destination", fullSQL, args)
+			return ex.sqlError(st, start, err, "queryScalarFn.14: scanning to destination")
 		}
+		ex.executed(st, start, 1)
 		ex.setCache(destinations, dtStruct)
 		return nil
 	}
 	if err := rows.Err(); err != nil {
-		return logSQLError(err, "queryScalarFn.20: iterating through rows", fullSQL, args)
+		return ex.sqlError(st, start, err, "queryScalarFn.20: iterating through rows")
 	}
 
+	ex.executed(st, start, 0)
 	return sql.ErrNoRows
 }
 
 func (ex *Execer) querySlice(dest interface{}) error {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.querySliceFn(dest)
 	}
@@ -301,7 +423,7 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 		reflect.ValueOf(dest)
 	}
 
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return err
 	}
@@ -314,10 +436,11 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 		log.Error("querySlice.2: Could not unmarshal cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	defer ex.counters.begin()()
+	start := time.Now()
+	rows, err := ex.database.Queryx(st.sql, st.args...)
 	if err != nil {
-		return logSQLError(err, "querySlice.load_all_values.query", fullSQL, args)
+		return ex.sqlError(st, start, err, "querySlice.load_all_values.query")
 	}
 
 	sliceValue := valueOfDest
@@ -329,7 +452,7 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 
 		err = rows.Scan(pointerToNewValue.Interface())
 		if err != nil {
-			return logSQLError(err, "querySlice.load_all_values.scan", fullSQL, args)
+			return ex.sqlError(st, start, err, "querySlice.load_all_values.scan")
 		}
 
 		// Append our new value to the slice:
@@ -338,8 +461,9 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 	valueOfDest.Set(sliceValue)
 
 	if err := rows.Err(); err != nil {
-		return logSQLError(err, "querySlice.load_all_values.rows_err", fullSQL, args)
+		return ex.sqlError(st, start, err, "querySlice.load_all_values.rows_err")
 	}
+	ex.executed(st, start, int64(sliceValue.Len()))
 
 	ex.setCache(dest, dtStruct)
 
@@ -347,6 +471,7 @@ func (ex *Execer) querySliceFn(dest interface{}) error {
 }
 
 func (ex *Execer) queryStruct(dest interface{}) error {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryStructFn(dest)
 	}
@@ -372,7 +497,7 @@ func (ex *Execer) queryStruct(dest interface{}) error {
 //
 // Returns sql.ErrNoRows if nothing was found
 func (ex *Execer) queryStructFn(dest interface{}) error {
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return err
 	}
@@ -385,17 +510,20 @@ func (ex *Execer) queryStructFn(dest interface{}) error {
 		log.Error("queryStruct.2: Could not unmarshal queryStruct cache data. Continuing with query")
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	err = ex.database.Get(dest, fullSQL, args...)
+	defer ex.counters.begin()()
+	start := time.Now()
+	err = ex.database.Get(dest, st.sql, st.args...)
 	if err != nil {
-		return logSQLError(err, "queryStruct.3", fullSQL, args)
+		return ex.sqlError(st, start, err, "queryStruct.3")
 	}
+	ex.executed(st, start, 1)
 
 	ex.setCache(dest, dtStruct)
 	return nil
 }
 
 func (ex *Execer) queryStructs(dest interface{}) error {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryStructsFn(dest)
 	}
@@ -422,7 +550,7 @@ func (ex *Execer) queryStructs(dest interface{}) error {
 // Returns the number of items found (which is not necessarily the # of items
 // set)
 func (ex *Execer) queryStructsFn(dest interface{}) error {
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		log.Error("queryStructs.1: Could not convert to SQL", "err", err)
 		return err
@@ -436,14 +564,16 @@ func (ex *Execer) queryStructsFn(dest interface{}) error {
 		log.Error("queryStructs.2: Could not unmarshal queryStruct cache data. Continuing with query", "err", err)
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	err = ex.database.Select(dest, fullSQL, args...)
+	defer ex.counters.begin()()
+	start := time.Now()
+	err = ex.database.Select(dest, st.sql, st.args...)
 	if err != nil {
-		logSQLError(err, "queryStructs", fullSQL, args)
+		return ex.sqlError(st, start, err, "queryStructs")
 	}
+	ex.executed(st, start, int64(reflect.Indirect(reflect.ValueOf(dest)).Len()))
 
 	ex.setCache(dest, dtStruct)
-	return err
+	return nil
 }
 
 // queryJSONStruct executes the query in builder and loads the resulting data into
@@ -462,6 +592,7 @@ func (ex *Execer) queryJSONStruct(dest interface{}) error {
 }
 
 func (ex *Execer) queryJSONBlob(single bool) ([]byte, error) {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryJSONBlobFn(single)
 	}
@@ -488,7 +619,7 @@ func (ex *Execer) queryJSONBlob(single bool) ([]byte, error) {
 //
 // Returns sql.ErrNoRows if nothing was found
 func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return nil, err
 	}
@@ -496,10 +627,11 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 		return blob, nil
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	rows, err := ex.database.Queryx(fullSQL, args...)
+	defer ex.counters.begin()()
+	start := time.Now()
+	rows, err := ex.database.Queryx(st.sql, st.args...)
 	if err != nil {
-		return nil, logSQLError(err, "queryJSONStructs", fullSQL, args)
+		return nil, ex.sqlError(st, start, err, "queryJSONStructs")
 	}
 
 	// TODO optimize this later, may be better to
@@ -510,11 +642,9 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 		for rows.Next() {
 			if i == 1 {
 				if dat.Strict {
-					logSQLError(errors.New("Multiple results returned"), "Expected single result", fullSQL, args)
-					log.Fatal("Expected single result, got many")
-				} else {
-					break
+					return nil, ex.sqlError(st, start, errors.New("Multiple results returned"), "Expected single result")
 				}
+				break
 			}
 			i++
 
@@ -545,6 +675,7 @@ func (ex *Execer) queryJSONBlobFn(single bool) ([]byte, error) {
 		}
 	}
 
+	ex.executed(st, start, int64(i))
 	if i == 0 {
 		return nil, sql.ErrNoRows
 	}
@@ -571,37 +702,43 @@ func (ex *Execer) queryJSONStructs(dest interface{}) error {
 
 // cacheOrSQL attempts to get a valeu from cache, otherwise it builds
 // the SQL and args to be executed. If value = "" then the SQL is built.
-// Returns sql, args, value, err.
-func (ex *Execer) cacheOrSQL() (string, []interface{}, []byte, error) {
+// Returns the statement, value, err.
+func (ex *Execer) cacheOrSQL() (*statement, []byte, error) {
+	cache, prefix := ex.cacheStore()
+
 	// if a cacheID exists, return the value ASAP
-	if Cache != nil && ex.cacheTTL > 0 && ex.cacheID != "" && !ex.cacheInvalidate {
-		v, err := Cache.Get(ex.cacheID)
+	if cache != nil && ex.cacheTTL > 0 && ex.cacheID != "" && !ex.cacheInvalidate {
+		v, err := cache.Get(prefix + ex.cacheID)
 		if err != nil && err != kvs.ErrNotFound {
-			log.Error("Unable to read cache key. Continuing with query", "key", ex.cacheID, "err", err)
+			log.Error("Unable to read cache key. Continuing with query", "key", prefix+ex.cacheID, "err", err)
 		} else if v != "" {
-			return "", nil, []byte(v), nil
+			ex.counters.cacheRead(true)
+			return nil, []byte(v), nil
 		}
+		ex.counters.cacheRead(false)
 	}
 
-	fullSQL, args, err := ex.Interpolate()
+	st, err := ex.statement()
 	if err != nil {
-		return "", nil, nil, err
+		return nil, nil, err
 	}
 
 	// if there is no cacheID, use the checksum of SQL as the ID
-	if Cache != nil && ex.cacheTTL > 0 && ex.cacheID == "" {
+	if cache != nil && ex.cacheTTL > 0 && ex.cacheID == "" {
 		// this must be set for setCache() to work below
-		ex.cacheID = kvs.Hash(fullSQL)
+		ex.cacheID = kvs.Hash(st.sql)
 
 		if !ex.cacheInvalidate {
-			v, err := Cache.Get(ex.cacheID)
+			v, err := cache.Get(prefix + ex.cacheID)
 			if v != "" && (err == nil || err != kvs.ErrNotFound) {
-				return "", nil, []byte(v), nil
+				ex.counters.cacheRead(true)
+				return nil, []byte(v), nil
 			}
+			ex.counters.cacheRead(false)
 		}
 	}
 
-	return fullSQL, args, nil, nil
+	return st, nil, nil
 }
 
 const (
@@ -615,19 +752,21 @@ const (
 // execer.cacheID is not set. data must be a string or a value that
 // can be json.Marshal'ed to string.
 func (ex *Execer) setCache(data interface{}, dataType int) {
-	if Cache == nil || ex.cacheTTL < 1 {
+	cache, prefix := ex.cacheStore()
+	if cache == nil || ex.cacheTTL < 1 {
 		return
 	}
+	key := prefix + ex.cacheID
 
 	var s string
 	switch dataType {
 	case dtStruct:
 		b, err := json.Marshal(data)
 		if err != nil {
-			log.Error("Could not marshal data, clearing", "key", ex.cacheID, "err", err)
-			err = Cache.Del(ex.cacheID)
+			log.Error("Could not marshal data, clearing", "key", key, "err", err)
+			err = cache.Del(key)
 			if err != nil {
-				log.Error("Could not delete cache key", "key", ex.cacheID, "err", err)
+				log.Error("Could not delete cache key", "key", key, "err", err)
 			}
 			return
 		}
@@ -638,13 +777,14 @@ func (ex *Execer) setCache(data interface{}, dataType int) {
 		s = string(data.([]byte))
 	}
 
-	err := Cache.Set(ex.cacheID, s, ex.cacheTTL)
+	err := cache.Set(key, s, ex.cacheTTL)
 	if err != nil {
 		log.Error("Could not set cache. Query will proceed without caching", "err", err)
 	}
 }
 
 func (ex *Execer) queryJSON() ([]byte, error) {
+	ex.route()
 	if ex.timeout == 0 {
 		return ex.queryJSONFn()
 	}
@@ -671,7 +811,10 @@ func (ex *Execer) queryJSON() ([]byte, error) {
 //
 // Returns sql.ErrNoRows if nothing was found
 func (ex *Execer) queryJSONFn() ([]byte, error) {
-	fullSQL, args, blob, err := ex.cacheOrSQL()
+	if !ex.sqlDialect().Supports(common.FeatureJSONAggregate) {
+		return nil, dat.NewError("QueryJSON and QueryObject are not supported by the dialect")
+	}
+	st, blob, err := ex.cacheOrSQL()
 	if err != nil {
 		return nil, err
 	}
@@ -679,16 +822,20 @@ func (ex *Execer) queryJSONFn() ([]byte, error) {
 		return blob, nil
 	}
 
-	defer logExecutionTime(time.Now(), fullSQL, args)
-	jsonSQL := fmt.Sprintf("SELECT TO_JSON(ARRAY_AGG(__datq.*)) FROM (%s) AS __datq", fullSQL)
+	defer ex.counters.begin()()
+	start := time.Now()
+	const jsonFmt = "SELECT TO_JSON(ARRAY_AGG(__datq.*)) FROM (%s) AS __datq"
+	st.sql = fmt.Sprintf(jsonFmt, st.sql)
+	st.logSQL = fmt.Sprintf(jsonFmt, st.logSQL)
 
-	err = ex.database.Get(&blob, jsonSQL, args...)
+	err = ex.database.Get(&blob, st.sql, st.args...)
 	if err != nil {
-		logSQLError(err, "queryJSON", jsonSQL, args)
+		return nil, ex.sqlError(st, start, err, "queryJSON")
 	}
+	ex.executed(st, start, -1)
 	ex.setCache(blob, dtBytes)
 
-	return blob, err
+	return blob, nil
 }
 
 // queryObject executes the query in builder and loads the resulting data into
@@ -3,9 +3,10 @@ package runner
 import (
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 	"github.com/oklog/ulid/v2"
@@ -16,6 +17,17 @@ type Execer struct {
 	database
 	builder dat.Builder
 
+	// primary is the database statements run on unless they are routed
+	// to a replica
+	primary  database
+	replicas *atomic.Pointer[replicaSet]
+
+	cache           *queryCache
+	logger          *queryLogger
+	redaction       *redaction
+	stats           *queryStats
+	counters        *dbCounters
+	dialect         dat.SQLDialect
 	cacheID         string
 	cacheTTL        time.Duration
 	cacheInvalidate bool
@@ -30,14 +42,27 @@ type Execer struct {
 
 const queryIDPrefix = "--dat:qid="
 
+// errTimeoutNotSupported is returned by statements with a timeout on a
+// database whose statements cannot be cancelled.
+var errTimeoutNotSupported = dat.NewError("Timeout is not supported by the dialect")
+
 // NewExecer creates a new instance of Execer.
 func NewExecer(database database, builder dat.Builder) *Execer {
 	return &Execer{
 		database: database,
 		builder:  builder,
+		primary:  database,
 	}
 }
 
+// sqlDialect returns the dialect of the statement, dat.Dialect if none.
+func (ex *Execer) sqlDialect() dat.SQLDialect {
+	if ex.dialect != nil {
+		return ex.dialect
+	}
+	return dat.Dialect
+}
+
 // Cache caches the results of queries for Select and SelectDoc.
 func (ex *Execer) Cache(id string, ttl time.Duration, invalidate bool) dat.Execer {
 	ex.cacheID = id
@@ -71,6 +96,9 @@ func (ex *Execer) Cancel() error {
 	if ex.queryID == "" {
 		return dat.ErrInvalidOperation
 	}
+	if !ex.sqlDialect().Supports(common.FeatureCancel) {
+		return errTimeoutNotSupported
+	}
 
 	q := fmt.Sprintf(`
 	SELECT pg_cancel_backend(psa.pid)
@@ -110,8 +138,8 @@ func (ex *Execer) Exec() (*dat.Result, error) {
 	return &dat.Result{RowsAffected: rowsAffected}, nil
 }
 
-// Queryx executes builder's query and returns rows.
-func (ex *Execer) Queryx() (*sqlx.Rows, error) {
+// Queryx executes builder's query and returns rows, which must be closed.
+func (ex *Execer) Queryx() (*Rows, error) {
 	return ex.query()
 }
 
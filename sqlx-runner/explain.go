@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/internal/log"
+	"github.com/nerdynz/dat/postgres"
+)
+
+// LogSlowPlans logs the plan of statements slower than the slow threshold,
+// see LogQueriesThreshold and LogOptions. Plans are explained without
+// ANALYZE so the statement is not executed again. Statements run in a
+// transaction are not explained.
+var LogSlowPlans bool
+
+// Explain returns the plan of the builder's statement. With
+// opts.Analyze the statement is executed, in a transaction which is rolled
+// back unless the builder is a select.
+func (ex *Execer) Explain(opts dat.ExplainOptions) (*dat.QueryPlan, error) {
+	st, err := ex.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := ex.explain(st, opts)
+	if err != nil {
+		return nil, err
+	}
+	return dat.ParseQueryPlan(blob)
+}
+
+func (ex *Execer) explain(st *statement, opts dat.ExplainOptions) ([]byte, error) {
+	if !ex.canExplain() {
+		return nil, dat.NewError("Explain requires Postgres")
+	}
+
+	st = &statement{
+		sql:         opts.ExplainSQL(st.sql),
+		args:        st.args,
+		logSQL:      opts.ExplainSQL(st.logSQL),
+		logArgs:     st.logArgs,
+		fingerprint: st.fingerprint,
+	}
+
+	if !opts.Analyze || isReadBuilder(ex.builder) {
+		return ex.explainIn(ex.database, st)
+	}
+
+	switch db := ex.database.(type) {
+	case *sqlx.DB:
+		tx, err := db.Beginx()
+		if err != nil {
+			return nil, dat.WrapSQLError("explain.begin", err)
+		}
+		defer tx.Rollback()
+		return ex.explainIn(tx, st)
+	case *sqlx.Tx:
+		if _, err := db.Exec("SAVEPOINT dat_explain"); err != nil {
+			return nil, dat.WrapSQLError("explain.savepoint", err)
+		}
+		blob, err := ex.explainIn(db, st)
+		if _, rbErr := db.Exec("ROLLBACK TO SAVEPOINT dat_explain"); rbErr != nil && err == nil {
+			err = dat.WrapSQLError("explain.rollback", rbErr)
+		}
+		return blob, err
+	default:
+		return nil, dat.NewError("cannot explain ANALYZE outside of a transaction")
+	}
+}
+
+func (ex *Execer) explainIn(db database, st *statement) ([]byte, error) {
+	start := time.Now()
+	var blob []byte
+	if err := db.Get(&blob, st.sql, st.args...); err != nil {
+		return nil, logSQLError(ex.logger, err, "explain", ex.kind(), start, st.logSQL, st.logArgs)
+	}
+	return blob, nil
+}
+
+// logSlowPlan logs the plan of a slow statement if LogSlowPlans is set.
+func (ex *Execer) logSlowPlan(st *statement, elapsed time.Duration) {
+	if !LogSlowPlans || !ex.canExplain() {
+		return
+	}
+	if _, ok := ex.database.(*sqlx.Tx); ok {
+		// the connection may still be reading the rows of the statement
+		return
+	}
+	threshold := LogQueriesThreshold
+	if ex.logger != nil {
+		threshold = ex.logger.opts.SlowThreshold
+	}
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+
+	blob, err := ex.explain(st, dat.ExplainOptions{})
+	if err != nil {
+		log.Error("logSlowPlan", "err", err)
+		return
+	}
+	ex.logger.plan(ex.kind(), elapsed, st.logSQL, blob)
+}
+
+// canExplain returns true if plans can be explained, which requires the JSON
+// plans of Postgres.
+func (ex *Execer) canExplain() bool {
+	_, ok := ex.sqlDialect().(*postgres.Postgres)
+	return ok
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nerdynz/dat/dat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainSelect(t *testing.T) {
+	installFixtures()
+
+	plan, err := testDB.Select("id", "name").From("people").Where("id = $1", 1).Explain(dat.ExplainOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plan.Plan.NodeType)
+	assert.True(t, plan.Plan.TotalCost > 0)
+	assert.Zero(t, plan.ExecutionTime)
+
+	plan, err = testDB.Select("id", "name").From("people").Explain(dat.ExplainOptions{Analyze: true, Buffers: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "people", plan.Plan.RelationName)
+	assert.EqualValues(t, 6, plan.Plan.ActualRows)
+	assert.Contains(t, plan.Plan.Properties, "Shared Hit Blocks")
+}
+
+func TestExplainAnalyzeWriteIsRolledBack(t *testing.T) {
+	installFixtures()
+
+	plan, err := testDB.Update("people").Set("name", "Explained").Where("id = $1", 1).Explain(dat.ExplainOptions{Analyze: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "ModifyTable", plan.Plan.NodeType)
+
+	var name string
+	assert.NoError(t, testDB.Select("name").From("people").Where("id = $1", 1).QueryScalar(&name))
+	assert.Equal(t, "Mario", name)
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	_, err = tx.DeleteFrom("people").Where("id = $1", 2).Explain(dat.ExplainOptions{Analyze: true})
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, tx.Select("count(*)").From("people").QueryScalar(&count))
+	assert.Equal(t, 6, count)
+}
+
+func TestLogSlowPlans(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{SlowThreshold: time.Nanosecond})
+	LogSlowPlans = true
+	defer func() { LogSlowPlans = false }()
+
+	var name string
+	assert.NoError(t, db.Select("name").From("people").Where("id = $1", 1).QueryScalar(&name))
+
+	var found bool
+	for _, record := range logRecords(t, buf) {
+		if record["msg"] == "slow query plan" {
+			found = true
+			assert.Contains(t, record["plan"], "Node Type")
+		}
+	}
+	assert.True(t, found)
+}
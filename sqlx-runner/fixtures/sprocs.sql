@@ -12,11 +12,11 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
---@key:"foobar"
+--@key=foobar
 INSERT INTO user_matches values(s, name)
 VALUES ('foo', 'bar');
 
---@key:"other"
+--@key=other
 INSERT INTO user_matches values(s, name)
 VALUES ('foo', 'bar');
 
@@ -5,19 +5,13 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff"
-	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 	"github.com/nerdynz/dat/kvs"
-	"github.com/nerdynz/dat/postgres"
 )
 
 // LogQueriesThreshold is the threshold for logging "slow" queries
 var LogQueriesThreshold time.Duration
 
-func init() {
-	dat.Dialect = postgres.New()
-}
-
 // Cache caches query results.
 var Cache kvs.KeyValueStore
 
@@ -30,21 +24,22 @@ func SetCache(store kvs.KeyValueStore) {
 // MustPing pings a database with an exponential backoff. The
 // function panics if the database cannot be pinged after 15 minutes
 func MustPing(db *sql.DB) {
-	var err error
+	if err := Ping(db, 15*time.Minute); err != nil {
+		panic("Could not ping database!")
+	}
+}
+
+// Ping pings a database with an exponential backoff, returning the last
+// error if the database cannot be pinged within maxElapsed.
+func Ping(db *sql.DB, maxElapsed time.Duration) error {
 	b := backoff.NewExponentialBackOff()
-	ticker := backoff.NewTicker(b)
+	b.MaxElapsedTime = maxElapsed
 
-	// Ticks will continue to arrive when the previous operation is still running,
-	// so operations that take a while to fail could run in quick succession.
-	for range ticker.C {
-		if err = db.Ping(); err != nil {
+	return backoff.Retry(func() error {
+		err := db.Ping()
+		if err != nil {
 			log.Debug("pinging database...", err.Error())
-			continue
 		}
-
-		ticker.Stop()
-		return
-	}
-
-	panic("Could not ping database!")
+		return err
+	}, b)
 }
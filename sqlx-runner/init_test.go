@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 	"github.com/nerdynz/dat/kvs"
@@ -16,6 +17,9 @@ import (
 var testDB *DB
 var sqlDB *sql.DB
 
+// deadDSN is the DSN of a server which refuses connections.
+const deadDSN = "postgres://nobody@127.0.0.1:1/nowhere?sslmode=disable&connect_timeout=1"
+
 func init() {
 	dat.Dialect = postgres.New()
 	sqlDB = realDb()
@@ -27,6 +31,17 @@ func init() {
 	//Cache, _ = kvs.NewDefaultRedisStore()
 }
 
+// newTestDB returns a DB on the test database whose logger, redaction,
+// statistics and replicas may be changed without affecting testDB.
+func newTestDB() *DB {
+	return NewDBFromSqlx(testDB.DB)
+}
+
+// newDeadDB returns a sqlx.DB on deadDSN.
+func newDeadDB() *sqlx.DB {
+	return sqlx.MustOpen("postgres", deadDSN)
+}
+
 func beginTxWithFixtures() *Tx {
 	installFixtures()
 	c, err := testDB.Begin()
@@ -2,9 +2,11 @@ package runner
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
 	"testing"
 
+	"github.com/lib/pq"
 	"github.com/lib/pq/hstore"
 	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/dat"
@@ -372,3 +374,24 @@ func TestInsertBytes(t *testing.T) {
 	assert.Exactly(t, b, image)
 	dat.EnableInterpolation = false
 }
+
+func TestInsertErrorClassification(t *testing.T) {
+	installFixtures()
+
+	_, err := testDB.InsertInto("people").Columns("id", "name").Values(1, "Dupe").Exec()
+	assert.True(t, dat.IsUniqueViolation(err))
+	assert.Equal(t, "people_pkey", dat.ConstraintName(err))
+	var pe *pq.Error
+	assert.True(t, errors.As(err, &pe))
+
+	_, err = testDB.InsertInto("posts").Columns("user_id", "title").Values(99999, "Orphan").Exec()
+	assert.True(t, dat.IsForeignKeyViolation(err))
+	assert.Equal(t, "posts_user_id_fkey", dat.ConstraintName(err))
+
+	_, err = testDB.InsertInto("people").Columns("email").Values("nameless@acme.com").Exec()
+	assert.True(t, dat.IsNotNullViolation(err))
+
+	var person Person
+	err = testDB.Select("*").From("people").Where("id = $1", 99999).QueryStruct(&person)
+	assert.True(t, dat.IsNoRows(err))
+}
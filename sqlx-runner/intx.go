@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/internal/log"
+)
+
+// InTxOptions are the options for InTx.
+type InTxOptions struct {
+	// TxOptions are the options the transaction is begun with.
+	TxOptions
+
+	// MaxRetries is the maximum number of times fn is retried after a
+	// serialization failure or deadlock. Defaults to 3, a negative value
+	// disables retries.
+	MaxRetries int
+
+	// InitialInterval is the wait before the first retry. It grows
+	// exponentially with each retry. Defaults to 50ms.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the wait between retries. Defaults to 1s.
+	MaxInterval time.Duration
+}
+
+func (opts *InTxOptions) backOff() backoff.BackOff {
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = 3
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 50 * time.Millisecond
+	if opts.InitialInterval > 0 {
+		b.InitialInterval = opts.InitialInterval
+	}
+	b.MaxInterval = time.Second
+	if opts.MaxInterval > 0 {
+		b.MaxInterval = opts.MaxInterval
+	}
+	b.MaxElapsedTime = 0
+	return backoff.WithMaxRetries(b, uint64(retries))
+}
+
+// InTx runs fn in a transaction. The transaction is committed if fn returns
+// nil and rolled back if fn returns an error or panics. A panic is re-raised
+// after the rollback.
+//
+// If fn or the commit fails with a serialization failure (40001) or deadlock
+// (40P01), the whole transaction is retried with exponential backoff, so fn
+// must be safe to run more than once. opts may be nil.
+func (db *DB) InTx(fn func(tx *Tx) error, opts *InTxOptions) error {
+	if opts == nil {
+		opts = &InTxOptions{}
+	}
+
+	return backoff.RetryNotify(func() error {
+		err := db.runInTx(fn, opts.TxOptions)
+		if err != nil && !isRetryableTxError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, opts.backOff(), func(err error, wait time.Duration) {
+		log.Debug("retrying transaction", "err", err, "wait", wait)
+	})
+}
+
+func (db *DB) runInTx(fn func(tx *Tx) error, txOpts TxOptions) (err error) {
+	tx, err := db.BeginWith(txOpts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.AutoRollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		if rbErr := tx.AutoRollback(); rbErr != nil {
+			log.Error("InTx.rollback_error", "err", rbErr)
+		}
+		return err
+	}
+
+	if !tx.isPending() {
+		// fn committed or rolled back explicitly
+		return nil
+	}
+	return tx.Commit()
+}
+
+// isPending returns true if neither Commit nor Rollback were called.
+func (tx *Tx) isPending() bool {
+	tx.Lock()
+	defer tx.Unlock()
+	return tx.state == txPending && !tx.IsRollbacked
+}
+
+// isRetryableTxError returns true if err is a serialization failure or a
+// deadlock, after which the transaction may succeed if retried.
+func isRetryableTxError(err error) bool {
+	return dat.IsSerializationFailure(err) || dat.IsDeadlock(err)
+}
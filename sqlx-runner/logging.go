@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/nerdynz/dat/internal/log"
+)
+
+// LogOptions configures the logging of the statements of a DB.
+type LogOptions struct {
+	// QueryLevel is the level of statements which succeed. Defaults to
+	// slog.LevelDebug.
+	QueryLevel slog.Leveler
+
+	// SlowLevel is the level of statements which take longer than
+	// SlowThreshold. Defaults to slog.LevelWarn.
+	SlowLevel slog.Leveler
+
+	// SlowThreshold is the duration after which a statement is slow.
+	// Defaults to LogQueriesThreshold, 0 means no statement is slow.
+	SlowThreshold time.Duration
+
+	// ErrorLevel is the level of statements which fail. Defaults to
+	// slog.LevelError.
+	ErrorLevel slog.Leveler
+
+	// RedactArgs omits the args attribute.
+	RedactArgs bool
+}
+
+// queryLogger logs the statements of a DB or Tx. A nil queryLogger logs
+// through the package level loggers set in dat.
+type queryLogger struct {
+	logger *slog.Logger
+	opts   LogOptions
+}
+
+// SetLogger sets the logger of the statements run on this DB and by any Tx
+// begun from it afterwards. Records have the attributes builder, sql, args,
+// elapsed, rows, err and tx_id when known. A nil logger reverts to the
+// package level loggers set through dat.SetSQLLogger and dat.SetErrorLogger.
+func (db *DB) SetLogger(logger *slog.Logger, opts LogOptions) {
+	if logger == nil {
+		db.logger = nil
+		return
+	}
+	if opts.QueryLevel == nil {
+		opts.QueryLevel = slog.LevelDebug
+	}
+	if opts.SlowLevel == nil {
+		opts.SlowLevel = slog.LevelWarn
+	}
+	if opts.SlowThreshold == 0 {
+		opts.SlowThreshold = LogQueriesThreshold
+	}
+	if opts.ErrorLevel == nil {
+		opts.ErrorLevel = slog.LevelError
+	}
+	db.logger = &queryLogger{logger: logger, opts: opts}
+}
+
+// Logger returns the logger set through SetLogger, or nil.
+func (db *DB) Logger() *slog.Logger {
+	if db.logger == nil {
+		return nil
+	}
+	return db.logger.logger
+}
+
+// withTx returns a queryLogger adding the tx_id attribute.
+func (ql *queryLogger) withTx(id uint64) *queryLogger {
+	if ql == nil {
+		return nil
+	}
+	return &queryLogger{logger: ql.logger.With(slog.Uint64("tx_id", id)), opts: ql.opts}
+}
+
+// executed logs a statement which succeeded. rows is the number of rows
+// returned or affected, -1 if unknown.
+func (ql *queryLogger) executed(kind string, start time.Time, statement string, args []interface{}, rows int64) {
+	if ql == nil {
+		logExecutionTime(start, statement, args)
+		return
+	}
+
+	elapsed := time.Since(start)
+	level := ql.opts.QueryLevel.Level()
+	msg := "query"
+	if ql.opts.SlowThreshold > 0 && elapsed > ql.opts.SlowThreshold {
+		level = ql.opts.SlowLevel.Level()
+		msg = "slow query"
+	}
+	ql.log(level, msg, kind, elapsed, statement, args, rows, nil)
+}
+
+// failed logs a statement which failed with err. op is the operation which
+// failed.
+func (ql *queryLogger) failed(kind string, op string, start time.Time, statement string, args []interface{}, err error) {
+	if ql == nil {
+		if err == sql.ErrNoRows {
+			if log.HasDebugLogger() {
+				log.Debug(op, "err", err, "sql", statement, "args", toOutputStr(args))
+			}
+		} else {
+			log.Error(op, "err", err, "sql", statement, "args", toOutputStr(args))
+		}
+		logExecutionTime(start, statement, args)
+		return
+	}
+
+	ql.log(ql.opts.ErrorLevel.Level(), "query failed", kind, time.Since(start), statement, args, -1, err)
+}
+
+// plan logs the plan of a slow statement.
+func (ql *queryLogger) plan(kind string, elapsed time.Duration, statement string, plan []byte) {
+	if ql == nil {
+		if log.HasSQLLogger() {
+			log.SQL("SLOW query plan", "elapsed", elapsed.String(), "sql", statement, "plan", string(plan))
+		}
+		return
+	}
+
+	ctx := context.Background()
+	level := ql.opts.SlowLevel.Level()
+	if !ql.logger.Enabled(ctx, level) {
+		return
+	}
+	ql.logger.LogAttrs(ctx, level, "slow query plan",
+		slog.String("builder", kind),
+		slog.String("sql", statement),
+		slog.Duration("elapsed", elapsed),
+		slog.String("plan", string(plan)),
+	)
+}
+
+func (ql *queryLogger) log(level slog.Level, msg string, kind string, elapsed time.Duration, statement string, args []interface{}, rows int64, err error) {
+	ctx := context.Background()
+	if !ql.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 6)
+	if kind != "" {
+		attrs = append(attrs, slog.String("builder", kind))
+	}
+	attrs = append(attrs, slog.String("sql", statement))
+	if len(args) > 0 && !ql.opts.RedactArgs {
+		attrs = append(attrs, slog.Any("args", redactArgs(args)))
+	}
+	attrs = append(attrs, slog.Duration("elapsed", elapsed))
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	ql.logger.LogAttrs(ctx, level, msg, attrs...)
+}
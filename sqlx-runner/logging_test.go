@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogDB(opts LogOptions) (*DB, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	db := newTestDB()
+	db.SetLogger(logger, opts)
+	return db, &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggerAttributes(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{})
+
+	var people []Person
+	err := db.Select("id", "name").From("people").Where("id > $1", 0).QueryStructs(&people)
+	assert.NoError(t, err)
+
+	records := logRecords(t, buf)
+	if assert.Len(t, records, 1) {
+		record := records[0]
+		assert.Equal(t, "DEBUG", record["level"])
+		assert.Equal(t, "query", record["msg"])
+		assert.Equal(t, "Select", record["builder"])
+		assert.Contains(t, record["sql"], "people")
+		assert.EqualValues(t, len(people), record["rows"])
+		assert.Contains(t, record, "elapsed")
+		assert.NotContains(t, record, "tx_id")
+	}
+}
+
+func TestLoggerTxAndErrors(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{RedactArgs: true})
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	_, err = tx.Update("people").Set("name", "Mario").Where("id = $1", 1).Exec()
+	assert.NoError(t, err)
+	err = tx.SQL("SELECT nope FROM people WHERE id = $1", 1).QueryScalar(new(int))
+	assert.Error(t, err)
+
+	records := logRecords(t, buf)
+	if assert.Len(t, records, 2) {
+		assert.EqualValues(t, tx.ID(), records[0]["tx_id"])
+		assert.EqualValues(t, 1, records[0]["rows"])
+		assert.NotContains(t, records[0], "args")
+
+		assert.Equal(t, "ERROR", records[1]["level"])
+		assert.Equal(t, "query failed", records[1]["msg"])
+		assert.Equal(t, "Raw", records[1]["builder"])
+		assert.Contains(t, records[1], "err")
+	}
+}
@@ -4,13 +4,38 @@ import (
 	"database/sql"
 	"fmt"
 	"hash/fnv"
+	"sort"
 
 	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 )
 
+// metaTableSQL creates the table used to track versions of functions and
+// migrations.
+const metaTableSQL = `
+CREATE TABLE IF NOT EXISTS dat__meta (
+	id serial primary key,
+	kind text,
+	name text,
+	version text,
+	meta1 text,
+	meta2 text,
+	meta3 text,
+	created_at timestamptz default now()
+)
+`
+
 // MustCreateMetaTable creates the dat__meta table or panics.
 func (db *DB) MustCreateMetaTable() {
+	err := db.CreateMetaTable()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CreateMetaTable creates the dat__meta table used to track versions of
+// functions and migrations.
+func (db *DB) CreateMetaTable() error {
 	// pg function to delete a function without having to worry about
 	// the arguments changing.
 	delfunc := `
@@ -30,51 +55,41 @@ exception when others then
 	-- find existing function
 END $$ LANGUAGE plpgsql;`
 
-	// The table used to track versions of functions and eventually
-	// migrations
-	createMeta := `
-CREATE TABLE IF NOT EXISTS dat__meta (
-	id serial primary key,
-	kind text,
-	name text,
-	version text,
-	meta1 text,
-	meta2 text,
-	meta3 text,
-	created_at timestamptz default now()
-)
-	`
-
 	tx, err := db.Begin()
 	if err != nil {
-		log.Fatal("Could not create session")
+		return err
 	}
 	defer tx.AutoRollback()
 
 	_, err = tx.ExecMulti(
 		dat.Expr(delfunc),
-		dat.Expr(createMeta),
+		dat.Expr(metaTableSQL),
 	)
 	if err != nil {
-		log.Fatal("Could not execute Multi SQL")
+		return fmt.Errorf("Could not create dat__meta: %w", err)
+	}
+	return tx.Commit()
+}
+
+// MustRegisterFunction registers a user defined function or panics, see
+// RegisterFunction.
+func (db *DB) MustRegisterFunction(name string, version string, body string) {
+	err := db.RegisterFunction(name, version, body)
+	if err != nil {
 		panic(err)
 	}
-	tx.Commit()
 }
 
-// MustRegisterFunction registers a user defined function but will not recreate it
+// RegisterFunction registers a user defined function but will not recreate it
 // unles the hash has changed with version. This is useful for keeping user defined
 // functions defined in source code.
-func (db *DB) MustRegisterFunction(name string, version string, body string) {
+func (db *DB) RegisterFunction(name string, version string, body string) error {
 	tx, err := db.Begin()
 	if err != nil {
-		log.Fatal("Could not register function", "err", err, "name", name)
+		return err
 	}
 	defer tx.AutoRollback()
 
-	if version == "" {
-	}
-
 	h := fnv.New64a()
 	h.Write([]byte(body))
 	crc := fmt.Sprintf("%s-%X", version, h.Sum64())
@@ -85,7 +100,7 @@ func (db *DB) MustRegisterFunction(name string, version string, body string) {
 		SQL(`SELECT id FROM dat__meta WHERE kind = 'function' AND version = $1 AND name = $2`, crc, name).
 		QueryScalar(&metaID)
 	if err != nil && err != sql.ErrNoRows {
-		log.Fatal("Could not get metadata for function", "err", err)
+		return fmt.Errorf("could not get metadata for function %s: %w", name, err)
 	}
 
 	if metaID == 0 {
@@ -107,40 +122,43 @@ func (db *DB) MustRegisterFunction(name string, version string, body string) {
 
 		_, err := tx.ExecMulti(commands...)
 		if err != nil {
-			log.Fatal("Could not insert function", "err", err)
+			return fmt.Errorf("could not create function %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RegisterFunctionsInDir registers the user defined functions marked with
+// --@sproc=Name in the .sql files of dir and its subdirectories, see
+// dat.ParseFromReader. version should be unique for each deployment to
+// properly upgrade the functions. Functions are registered in order of name.
+func (db *DB) RegisterFunctionsInDir(dir string, version string) error {
+	_, sprocs, err := dat.ParseDir(dir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(sprocs))
+	for name := range sprocs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err = db.RegisterFunction(name, version, sprocs[name])
+		if err != nil {
+			return err
 		}
 	}
-	tx.Commit()
+	return nil
 }
 
 // MustRegisterFunctionsInDir registers user defined functions in the given
-// dir. version should be unique for each deployment to properly upgrade the
-// function.
-// func (db *DB) MustRegisterFunctionsInDir(dir string, version string) {
-// 	keys := map[string]string{}
-// 	sprocs := map[string]string{}
-// 	walkFn := func(path string, fi os.FileInfo, err error) error {
-// 		if fi.IsDir() {
-// 			return nil
-// 		}
-// 		log.Debug("MustRegisterFunctionsInDir", "dir", dir, "path", path)
-
-// 		if filepath.Ext(path) == ".sql" {
-// 			f, err := os.Open(path)
-// 			if err != nil {
-// 				log.Fatal("Could not open SQL file.", "file", path)
-// 			}
-// 			err = dat.ParseFromReader(f, keys, sprocs)
-// 			if err != nil {
-// 				log.Fatal("Could not parse SQL file.", "err", err)
-// 			}
-// 		}
-// 		return nil
-// 	}
-
-// 	filepath.Walk(dir, walkFn)
-
-// 	for name, body := range sprocs {
-// 		db.MustRegisterFunction(name, version, body)
-// 	}
-// }
+// dir or panics. version should be unique for each deployment to properly
+// upgrade the function.
+func (db *DB) MustRegisterFunctionsInDir(dir string, version string) {
+	err := db.RegisterFunctionsInDir(dir, version)
+	if err != nil {
+		panic(err)
+	}
+}
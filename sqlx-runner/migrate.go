@@ -0,0 +1,391 @@
+package runner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/postgres"
+)
+
+// Migration is a versioned schema change. Either the SQL or the Func of each
+// direction is set. Down is optional, a migration without one cannot be
+// reverted.
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	UpFunc   func(tx *Tx) error
+	DownFunc func(tx *Tx) error
+}
+
+func (m *Migration) up(tx *Tx) error {
+	if m.UpFunc != nil {
+		return m.UpFunc(tx)
+	}
+	_, err := tx.Tx.Exec(m.UpSQL)
+	return err
+}
+
+func (m *Migration) hasDown() bool {
+	return m.DownFunc != nil || m.DownSQL != ""
+}
+
+func (m *Migration) down(tx *Tx) error {
+	if m.DownFunc != nil {
+		return m.DownFunc(tx)
+	}
+	_, err := tx.Tx.Exec(m.DownSQL)
+	return err
+}
+
+// MigrationStatus is the state of a migration.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Missing is true if the migration was applied but is no longer known
+	// to the Migrator.
+	Missing bool
+}
+
+// Migrator applies migrations and records the applied versions in dat__meta.
+// Each migration runs in its own transaction holding an advisory lock, so
+// several processes may migrate the same database concurrently. Migrations
+// require Postgres.
+type Migrator struct {
+	db         *DB
+	migrations []*Migration
+}
+
+// migrationLockKey is the key of the advisory lock held while migrating.
+var migrationLockKey = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("dat__meta.migration"))
+	return int64(h.Sum64())
+}()
+
+// NewMigrator creates a Migrator for this database.
+func (db *DB) NewMigrator() *Migrator {
+	return &Migrator{db: db}
+}
+
+// Add adds migrations. Versions must be unique.
+func (m *Migrator) Add(migrations ...*Migration) error {
+	for _, mig := range migrations {
+		if mig.UpFunc == nil && mig.UpSQL == "" {
+			return fmt.Errorf("migration %d %s has no up migration", mig.Version, mig.Name)
+		}
+		if m.find(mig.Version) != nil {
+			return fmt.Errorf("duplicate migration version %d", mig.Version)
+		}
+		m.migrations = append(m.migrations, mig)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return nil
+}
+
+// AddFunc adds a migration written in Go. down may be nil.
+func (m *Migrator) AddFunc(version int64, name string, up, down func(tx *Tx) error) error {
+	return m.Add(&Migration{Version: version, Name: name, UpFunc: up, DownFunc: down})
+}
+
+// LoadDir adds the SQL migrations in dir, see LoadFS.
+func (m *Migrator) LoadDir(dir string) error {
+	return m.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS adds the SQL migrations in dir of fsys, such as an embed.FS. Files
+// are named VERSION_NAME.up.sql and VERSION_NAME.down.sql, for example
+// 0001_create_users.up.sql. Other files are ignored.
+func (m *Migrator) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		version, name, direction, ok := parseMigrationFilename(entry.Name())
+		if !ok {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		} else if mig.Name != name {
+			return fmt.Errorf("duplicate migration version %d: %s and %s", version, mig.Name, name)
+		}
+		if direction == "up" {
+			mig.UpSQL = string(b)
+		} else {
+			mig.DownSQL = string(b)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, mig)
+	}
+	return m.Add(migrations...)
+}
+
+// parseMigrationFilename parses VERSION_NAME.up.sql and VERSION_NAME.down.sql.
+func parseMigrationFilename(filename string) (version int64, name string, direction string, ok bool) {
+	base := strings.TrimSuffix(filename, ".sql")
+	if base == filename {
+		return 0, "", "", false
+	}
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", false
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	parts := strings.SplitN(base, "_", 2)
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", "", false
+	}
+	if len(parts) == 2 {
+		name = parts[1]
+	}
+	return version, name, direction, true
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
+
+// Up applies all pending migrations in order of version. It returns the
+// number of migrations applied.
+func (m *Migrator) Up() (int, error) {
+	n := 0
+	for {
+		applied := false
+		err := m.inLockedTx(func(tx *Tx, versions []appliedMigration) error {
+			done := map[int64]bool{}
+			for _, v := range versions {
+				done[v.Version] = true
+			}
+			for _, mig := range m.migrations {
+				if done[mig.Version] {
+					continue
+				}
+				applied = true
+				return m.apply(tx, mig)
+			}
+			return nil
+		})
+		if err != nil || !applied {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Down reverts the last n applied migrations. It returns the number of
+// migrations reverted.
+func (m *Migrator) Down(n int) (int, error) {
+	for i := 0; i < n; i++ {
+		reverted := false
+		err := m.inLockedTx(func(tx *Tx, versions []appliedMigration) error {
+			if len(versions) == 0 {
+				return nil
+			}
+			mig, err := m.revertible(versions[len(versions)-1])
+			if err != nil {
+				return err
+			}
+			reverted = true
+			return m.revert(tx, mig)
+		})
+		if err != nil || !reverted {
+			return i, err
+		}
+	}
+	return n, nil
+}
+
+// Redo reverts and reapplies the last applied migration in a single
+// transaction.
+func (m *Migrator) Redo() error {
+	return m.inLockedTx(func(tx *Tx, versions []appliedMigration) error {
+		if len(versions) == 0 {
+			return fmt.Errorf("no migration has been applied")
+		}
+		mig, err := m.revertible(versions[len(versions)-1])
+		if err != nil {
+			return err
+		}
+		if err := m.revert(tx, mig); err != nil {
+			return err
+		}
+		return m.apply(tx, mig)
+	})
+}
+
+// Status returns the state of all known and applied migrations in order of
+// version. It only reads dat__meta, without waiting for running migrations.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.checkDialect(); err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := m.db.DB.Get(&exists, `SELECT to_regclass('dat__meta') IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	var versions []appliedMigration
+	if exists {
+		var err error
+		if versions, err = selectAppliedMigrations(m.db.DB); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []MigrationStatus
+	applied := map[int64]appliedMigration{}
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if v, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = v.CreatedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, v := range applied {
+		statuses = append(statuses, MigrationStatus{
+			Version:   v.Version,
+			Name:      v.Name,
+			Applied:   true,
+			AppliedAt: v.CreatedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func (m *Migrator) revertible(applied appliedMigration) (*Migration, error) {
+	mig := m.find(applied.Version)
+	if mig == nil {
+		return nil, fmt.Errorf("applied migration %d %s is unknown", applied.Version, applied.Name)
+	}
+	if !mig.hasDown() {
+		return nil, fmt.Errorf("migration %d %s has no down migration", mig.Version, mig.Name)
+	}
+	return mig, nil
+}
+
+func (m *Migrator) apply(tx *Tx, mig *Migration) error {
+	if err := mig.up(tx); err != nil {
+		return fmt.Errorf("migration %d %s up: %w", mig.Version, mig.Name, err)
+	}
+	_, err := tx.Tx.Exec(
+		`INSERT INTO dat__meta (kind, version, name) VALUES ('migration', $1, $2)`,
+		strconv.FormatInt(mig.Version, 10), mig.Name,
+	)
+	return err
+}
+
+func (m *Migrator) revert(tx *Tx, mig *Migration) error {
+	if err := mig.down(tx); err != nil {
+		return fmt.Errorf("migration %d %s down: %w", mig.Version, mig.Name, err)
+	}
+	_, err := tx.Tx.Exec(
+		`DELETE FROM dat__meta WHERE kind = 'migration' AND version = $1`,
+		strconv.FormatInt(mig.Version, 10),
+	)
+	return err
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// errMigrationsRequirePostgres is returned by the Migrator of a database
+// other than Postgres, since migrations rely on advisory locks.
+var errMigrationsRequirePostgres = dat.NewError("migrations require Postgres")
+
+// checkDialect returns errMigrationsRequirePostgres unless the database is
+// Postgres.
+func (m *Migrator) checkDialect() error {
+	if _, ok := m.db.Dialect().(*postgres.Postgres); !ok {
+		return errMigrationsRequirePostgres
+	}
+	return nil
+}
+
+// inLockedTx runs fn in a transaction holding the migration lock, passing
+// the applied migrations in order of version.
+func (m *Migrator) inLockedTx(fn func(tx *Tx, applied []appliedMigration) error) error {
+	if err := m.checkDialect(); err != nil {
+		return err
+	}
+	return m.db.InTx(func(tx *Tx) error {
+		if _, err := tx.Tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockKey); err != nil {
+			return err
+		}
+		if _, err := tx.Tx.Exec(metaTableSQL); err != nil {
+			return err
+		}
+
+		applied, err := selectAppliedMigrations(tx.Tx)
+		if err != nil {
+			return err
+		}
+		return fn(tx, applied)
+	}, &InTxOptions{MaxRetries: -1})
+}
+
+// selectAppliedMigrations returns the applied migrations in order of
+// version.
+func selectAppliedMigrations(db database) ([]appliedMigration, error) {
+	var applied []appliedMigration
+	err := db.Select(&applied, `
+		SELECT version::bigint AS version, coalesce(name, '') AS name, created_at
+		FROM dat__meta
+		WHERE kind = 'migration'
+		ORDER BY version::bigint
+	`)
+	return applied, err
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetMigrations() {
+	testDB.MustCreateMetaTable()
+	testDB.SQL(`DROP TABLE IF EXISTS migrate_widgets`).Exec()
+	testDB.SQL(`DELETE FROM dat__meta WHERE kind = 'migration'`).Exec()
+}
+
+func TestParseMigrationFilename(t *testing.T) {
+	version, name, direction, ok := parseMigrationFilename("0001_create_users.up.sql")
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, version)
+	assert.Equal(t, "create_users", name)
+	assert.Equal(t, "up", direction)
+
+	_, _, direction, ok = parseMigrationFilename("20200101_x.down.sql")
+	assert.True(t, ok)
+	assert.Equal(t, "down", direction)
+
+	_, _, _, ok = parseMigrationFilename("README.md")
+	assert.False(t, ok)
+	_, _, _, ok = parseMigrationFilename("create_users.up.sql")
+	assert.False(t, ok)
+}
+
+func TestMigrator(t *testing.T) {
+	resetMigrations()
+	defer resetMigrations()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_widgets.up.sql":   {Data: []byte(`CREATE TABLE migrate_widgets (id serial primary key)`)},
+		"migrations/0001_widgets.down.sql": {Data: []byte(`DROP TABLE migrate_widgets`)},
+		"migrations/0002_name.up.sql":      {Data: []byte(`ALTER TABLE migrate_widgets ADD COLUMN name text`)},
+		"migrations/0002_name.down.sql":    {Data: []byte(`ALTER TABLE migrate_widgets DROP COLUMN name`)},
+	}
+	m := testDB.NewMigrator()
+	assert.NoError(t, m.LoadFS(fsys, "migrations"))
+	assert.NoError(t, m.AddFunc(3, "seed", func(tx *Tx) error {
+		_, err := tx.InsertInto("migrate_widgets").Columns("name").Values("sprocket").Exec()
+		return err
+	}, func(tx *Tx) error {
+		_, err := tx.DeleteFrom("migrate_widgets").Exec()
+		return err
+	}))
+	assert.Error(t, m.AddFunc(3, "duplicate", func(tx *Tx) error { return nil }, nil))
+
+	n, err := m.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = m.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	var count int
+	assert.NoError(t, testDB.SQL(`SELECT count(*) FROM migrate_widgets`).QueryScalar(&count))
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, m.Redo())
+	assert.NoError(t, testDB.SQL(`SELECT count(*) FROM migrate_widgets`).QueryScalar(&count))
+	assert.Equal(t, 1, count)
+
+	n, err = m.Down(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	statuses, err := m.Status()
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 3) {
+		assert.True(t, statuses[0].Applied)
+		assert.Equal(t, "widgets", statuses[0].Name)
+		assert.False(t, statuses[1].Applied)
+		assert.False(t, statuses[2].Applied)
+	}
+}
+
+func TestMigratorFailureRollsBack(t *testing.T) {
+	resetMigrations()
+	defer resetMigrations()
+
+	m := testDB.NewMigrator()
+	assert.NoError(t, m.Add(
+		&Migration{Version: 1, Name: "widgets", UpSQL: `CREATE TABLE migrate_widgets (id serial primary key)`},
+		&Migration{Version: 2, Name: "broken", UpSQL: `ALTER TABLE migrate_widgets ADD COLUMN`},
+	))
+
+	n, err := m.Up()
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+
+	statuses, err := m.Status()
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	}
+}
@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nerdynz/dat/internal/log"
+)
+
+// PoolOptions configures the connection pool of a DB. Zero fields keep the
+// database/sql defaults.
+type PoolOptions struct {
+	// MaxOpenConns is the maximum number of open connections.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections. A negative
+	// value keeps no idle connection.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum time a connection may be reused.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+}
+
+func (opts PoolOptions) apply(db *sql.DB) {
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+}
+
+// SetPool configures the connection pool.
+func (db *DB) SetPool(opts PoolOptions) {
+	opts.apply(db.DB.DB)
+}
+
+// DBStats are the statistics of a DB.
+type DBStats struct {
+	sql.DBStats
+
+	// InFlight is the number of statements being executed, including those
+	// whose rows returned by Queryx are not closed yet.
+	InFlight int64
+	// Queries is the number of statements executed.
+	Queries uint64
+	// OpenTransactions is the number of transactions begun and not yet
+	// committed or rolled back.
+	OpenTransactions int64
+	// CacheHits and CacheMisses count the reads of cached queries.
+	CacheHits   uint64
+	CacheMisses uint64
+
+	// Healthy is false if the last health check failed. See
+	// StartHealthCheck.
+	Healthy bool
+}
+
+// Stats returns the statistics of the connection pool and of the
+// statements run on this DB and its transactions.
+func (db *DB) Stats() DBStats {
+	stats := DBStats{DBStats: db.DB.Stats(), Healthy: true}
+	if c := db.counters; c != nil {
+		stats.InFlight = c.inFlight.Load()
+		stats.Queries = c.queries.Load()
+		stats.OpenTransactions = c.openTx.Load()
+		stats.CacheHits = c.cacheHits.Load()
+		stats.CacheMisses = c.cacheMisses.Load()
+	}
+	stats.Healthy, _ = db.Healthy()
+	return stats
+}
+
+// dbCounters are the counters shared by a DB and its transactions.
+type dbCounters struct {
+	inFlight    atomic.Int64
+	queries     atomic.Uint64
+	openTx      atomic.Int64
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+}
+
+// begin counts a statement as in flight until the returned func is called.
+func (c *dbCounters) begin() func() {
+	if c == nil {
+		return func() {}
+	}
+	c.inFlight.Add(1)
+	c.queries.Add(1)
+	return func() { c.inFlight.Add(-1) }
+}
+
+func (c *dbCounters) txBegun() {
+	if c != nil {
+		c.openTx.Add(1)
+	}
+}
+
+func (c *dbCounters) txEnded() {
+	if c != nil {
+		c.openTx.Add(-1)
+	}
+}
+
+func (c *dbCounters) cacheRead(hit bool) {
+	if c == nil {
+		return
+	}
+	if hit {
+		c.cacheHits.Add(1)
+	} else {
+		c.cacheMisses.Add(1)
+	}
+}
+
+// HealthCheckOptions are the options of StartHealthCheck.
+type HealthCheckOptions struct {
+	// Interval is the interval between pings. Defaults to 10s.
+	Interval time.Duration
+	// Timeout is the time a ping may take. Defaults to 1s.
+	Timeout time.Duration
+	// OnStateChange is called when the database becomes unhealthy, with the
+	// error of the failed ping, and when it becomes healthy again, with nil.
+	OnStateChange func(healthy bool, err error)
+}
+
+type healthCheck struct {
+	sync.Mutex
+	healthy bool
+	err     error
+	stop    chan struct{}
+}
+
+// StartHealthCheck pings the database periodically. The result is
+// reported by Healthy and Stats, and state changes through
+// opts.OnStateChange. Calling it again restarts the health check with opts.
+func (db *DB) StartHealthCheck(opts HealthCheckOptions) {
+	db.StopHealthCheck()
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+
+	hc := &healthCheck{healthy: true, stop: make(chan struct{})}
+	db.healthMu.Lock()
+	db.health = hc
+	db.healthMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hc.stop:
+				return
+			case <-ticker.C:
+				hc.check(db.DB.DB, opts)
+			}
+		}
+	}()
+}
+
+// StopHealthCheck stops the health check started by StartHealthCheck.
+func (db *DB) StopHealthCheck() {
+	db.healthMu.Lock()
+	defer db.healthMu.Unlock()
+	if db.health != nil {
+		close(db.health.stop)
+		db.health = nil
+	}
+}
+
+// Healthy returns false and the error of the last ping if the last health
+// check failed. It returns true if health checks are not running.
+func (db *DB) Healthy() (bool, error) {
+	db.healthMu.Lock()
+	hc := db.health
+	db.healthMu.Unlock()
+	if hc == nil {
+		return true, nil
+	}
+	hc.Lock()
+	defer hc.Unlock()
+	return hc.healthy, hc.err
+}
+
+func (hc *healthCheck) check(db *sql.DB, opts HealthCheckOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	err := db.PingContext(ctx)
+
+	hc.Lock()
+	changed := hc.healthy != (err == nil)
+	hc.healthy = err == nil
+	hc.err = err
+	hc.Unlock()
+
+	if !changed {
+		return
+	}
+	if err != nil {
+		log.Error("database is unhealthy", "err", err)
+	} else {
+		log.Debug("database is healthy again")
+	}
+	if opts.OnStateChange != nil {
+		opts.OnStateChange(err == nil, err)
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenPoolOptions(t *testing.T) {
+	db, err := Open(os.Getenv("DAT_DRIVER"), os.Getenv("DAT_DSN"), OpenOptions{
+		PingRetry: time.Second,
+		Pool:      PoolOptions{MaxOpenConns: 3, MaxIdleConns: 1, ConnMaxLifetime: time.Minute},
+	})
+	assert.NoError(t, err)
+	defer db.DB.Close()
+	assert.Equal(t, 3, db.Stats().MaxOpenConnections)
+}
+
+func TestStats(t *testing.T) {
+	installFixtures()
+	db, err := OpenDB(sqlDB, "postgres")
+	assert.NoError(t, err)
+	before := db.Stats()
+
+	var name string
+	assert.NoError(t, db.Select("name").From("people").Where("id = $1", 1).QueryScalar(&name))
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.EqualValues(t, before.OpenTransactions+1, db.Stats().OpenTransactions)
+	assert.NoError(t, tx.Select("name").From("people").Where("id = $1", 2).QueryScalar(&name))
+	assert.NoError(t, tx.Commit())
+
+	n, err := db.ExecMulti(dat.Expr("SELECT 1"), dat.Expr("SELECT $1::int", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	rows, err := db.Select("name").From("people").Execer.(*Execer).Queryx()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, db.Stats().InFlight)
+	assert.NoError(t, rows.Close())
+
+	stats := db.Stats()
+	assert.Equal(t, before.Queries+5, stats.Queries)
+	assert.EqualValues(t, 0, stats.InFlight)
+	assert.Equal(t, before.OpenTransactions, stats.OpenTransactions)
+	assert.True(t, stats.Healthy)
+	assert.True(t, stats.OpenConnections > 0)
+
+	for i := 0; i < 2; i++ {
+		err = db.Select("name").From("people").Where("id = $1", 3).Cache("stats-test", time.Second, false).QueryScalar(&name)
+		assert.NoError(t, err)
+	}
+	stats = db.Stats()
+	assert.True(t, stats.CacheMisses > before.CacheMisses)
+	assert.True(t, stats.CacheHits > before.CacheHits)
+}
+
+func TestHealthCheck(t *testing.T) {
+	db := newDB(newDeadDB(), postgres.New())
+
+	changes := make(chan bool, 1)
+	db.StartHealthCheck(HealthCheckOptions{
+		Interval: 10 * time.Millisecond,
+		OnStateChange: func(healthy bool, err error) {
+			assert.Error(t, err)
+			changes <- healthy
+		},
+	})
+	defer db.StopHealthCheck()
+
+	select {
+	case healthy := <-changes:
+		assert.False(t, healthy)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no state change")
+	}
+	healthy, err := db.Healthy()
+	assert.False(t, healthy)
+	assert.Error(t, err)
+	assert.False(t, db.Stats().Healthy)
+}
@@ -3,6 +3,8 @@ package runner
 import (
 	"database/sql"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/nerdynz/dat/dat"
 	"github.com/jmoiron/sqlx"
@@ -11,29 +13,71 @@ import (
 // Queryable is an object that can be queried.
 type Queryable struct {
 	runner database
+	cache     *queryCache
+	logger    *queryLogger
+	redaction *redaction
+	stats     *queryStats
+	replicas  atomic.Pointer[replicaSet]
+	counters  *dbCounters
+	dialect   dat.SQLDialect
 }
 
 // WrapSqlxExt converts a sqlx.Ext to a *Queryable
 func WrapSqlxExt(e sqlx.Ext) (*Queryable, error) {
+	// an unknown driver writes in dat.Dialect
+	dialect, _ := dialectFor(e.DriverName())
 	switch e := e.(type) {
 	default:
 		return nil, dat.NewError(fmt.Sprintf("unexpected type %T", e))
 	case database:
-		return &Queryable{e}, nil
+		return &Queryable{runner: e, dialect: dialect}, nil
 	}
 }
 
+// Dialect returns the dialect of the statements of this Queryable.
+func (q *Queryable) Dialect() dat.SQLDialect {
+	if q.dialect != nil {
+		return q.dialect
+	}
+	return dat.Dialect
+}
+
+// newExecer creates an Execer for b which inherits this Queryable's settings.
+func (q *Queryable) newExecer(b dat.Builder) *Execer {
+	ex := NewExecer(q.runner, b)
+	ex.replicas = &q.replicas
+	ex.cache = q.cache
+	ex.logger = q.logger
+	ex.redaction = q.redaction
+	ex.stats = q.stats
+	ex.counters = q.counters
+	ex.dialect = q.dialect
+	if setter, ok := b.(dat.DialectSetter); ok && q.dialect != nil {
+		setter.SetDialect(q.dialect)
+	}
+	q.redaction.apply(b)
+	return ex
+}
+
+// fingerprint returns the fingerprint of sql if statistics are enabled.
+func (q *Queryable) fingerprint(sql string) string {
+	if q.stats == nil {
+		return ""
+	}
+	return Fingerprint(sql)
+}
+
 // Call creates a new CallBuilder for the given sproc and args.
 func (q *Queryable) Call(sproc string, args ...interface{}) *dat.CallBuilder {
 	b := dat.NewCallBuilder(sproc, args...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // DeleteFrom creates a new DeleteBuilder for the given table.
 func (q *Queryable) DeleteFrom(table string) *dat.DeleteBuilder {
 	b := dat.NewDeleteBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
@@ -41,6 +85,13 @@ func (q *Queryable) DeleteFrom(table string) *dat.DeleteBuilder {
 func (q *Queryable) Exec(cmd string, args ...interface{}) (*dat.Result, error) {
 	var result sql.Result
 	var err error
+	fingerprint := q.fingerprint(cmd)
+	cmd, args, err = q.Dialect().Rebind(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	defer q.counters.begin()()
+	start := time.Now()
 
 	if len(args) == 0 {
 		result, err = q.runner.Exec(cmd)
@@ -48,39 +99,52 @@ func (q *Queryable) Exec(cmd string, args ...interface{}) (*dat.Result, error) {
 		result, err = q.runner.Exec(cmd, args...)
 	}
 	if err != nil {
-		return nil, logSQLError(err, "Exec", cmd, args)
+		q.stats.record(fingerprint, time.Since(start), -1, true)
+		return nil, logSQLError(q.logger, err, "Exec", "Exec", start, cmd, q.redaction.args(args))
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return nil, logSQLError(err, "Exec", cmd, args)
+		q.stats.record(fingerprint, time.Since(start), -1, true)
+		return nil, logSQLError(q.logger, err, "Exec", "Exec", start, cmd, q.redaction.args(args))
+	}
+	q.stats.record(fingerprint, time.Since(start), rowsAffected, false)
+	if q.logger != nil {
+		q.logger.executed("Exec", start, cmd, q.redaction.args(args), rowsAffected)
 	}
 	return &dat.Result{RowsAffected: rowsAffected}, nil
 }
 
 // ExecBuilder executes the SQL in builder.
 func (q *Queryable) ExecBuilder(b dat.Builder) error {
-	sql, args, err := b.Interpolate()
+	st, err := q.newExecer(b).statement()
 	if err != nil {
 		return err
 	}
+	defer q.counters.begin()()
+	start := time.Now()
 
-	if len(args) == 0 {
-		_, err = q.runner.Exec(sql)
+	if len(st.args) == 0 {
+		_, err = q.runner.Exec(st.sql)
 	} else {
-		_, err = q.runner.Exec(sql, args...)
+		_, err = q.runner.Exec(st.sql, st.args...)
 	}
 	if err != nil {
-		return logSQLError(err, "ExecBuilder", sql, args)
+		q.stats.record(st.fingerprint, time.Since(start), -1, true)
+		return logSQLError(q.logger, err, "ExecBuilder", builderKind(b), start, st.logSQL, st.logArgs)
+	}
+	q.stats.record(st.fingerprint, time.Since(start), -1, false)
+	if q.logger != nil {
+		q.logger.executed(builderKind(b), start, st.logSQL, st.logArgs, -1)
 	}
 	return nil
 }
 
 // ExecMulti executes multiple SQL statements returning the number of
-// statements executed, or the index at which an error occurred.
+// statements executed, or the index at which an error occurred. Each
+// statement is logged and counted as by Exec.
 func (q *Queryable) ExecMulti(commands ...*dat.Expression) (int, error) {
 	for i, cmd := range commands {
-		_, err := q.runner.Exec(cmd.Sql, cmd.Args...)
-		if err != nil {
+		if _, err := q.Exec(cmd.Sql, cmd.Args...); err != nil {
 			return i, err
 		}
 	}
@@ -90,48 +154,48 @@ func (q *Queryable) ExecMulti(commands ...*dat.Expression) (int, error) {
 // InsertInto creates a new InsertBuilder for the given table.
 func (q *Queryable) InsertInto(table string) *dat.InsertBuilder {
 	b := dat.NewInsertBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Insect inserts or selects.
 func (q *Queryable) Insect(table string) *dat.InsectBuilder {
 	b := dat.NewInsectBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Select creates a new SelectBuilder for the given columns.
 func (q *Queryable) Select(columns ...string) *dat.SelectBuilder {
 	b := dat.NewSelectBuilder(columns...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // SelectDoc creates a new SelectBuilder for the given columns.
 func (q *Queryable) SelectDoc(columns ...string) *dat.SelectDocBuilder {
 	b := dat.NewSelectDocBuilder(columns...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // SQL creates a new raw SQL builder.
 func (q *Queryable) SQL(sql string, args ...interface{}) *dat.RawBuilder {
 	b := dat.NewRawBuilder(sql, args...)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Update creates a new UpdateBuilder for the given table.
 func (q *Queryable) Update(table string) *dat.UpdateBuilder {
 	b := dat.NewUpdateBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
 
 // Upsert creates a new UpdateBuilder for the given table.
 func (q *Queryable) Upsert(table string) *dat.UpsertBuilder {
 	b := dat.NewUpsertBuilder(table)
-	b.Execer = NewExecer(q.runner, b)
+	b.Execer = q.newExecer(b)
 	return b
 }
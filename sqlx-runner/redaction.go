@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"path"
+	"strings"
+
+	"github.com/nerdynz/dat/common"
+	"github.com/nerdynz/dat/dat"
+)
+
+// RedactionPolicy controls which arguments of the statements of a DB appear
+// in logs and errors. Values wrapped by dat.Secret are always masked.
+type RedactionPolicy struct {
+	// Columns are patterns of the columns whose values are masked, such as
+	// "password" or "*_token", matched case-insensitively with path.Match.
+	// They apply to the values of InsertBuilder and UpdateBuilder.
+	Columns []string
+
+	// SuppressArgs omits all arguments. Interpolated statements are logged
+	// with placeholders instead of values.
+	SuppressArgs bool
+}
+
+// redaction is a validated RedactionPolicy.
+type redaction struct {
+	columns      []string
+	suppressArgs bool
+}
+
+// SetRedactionPolicy sets the redaction policy of the statements run on
+// this DB and by any Tx begun from it afterwards. It returns an error if a
+// column pattern is malformed.
+func (db *DB) SetRedactionPolicy(policy RedactionPolicy) error {
+	columns := make([]string, len(policy.Columns))
+	for i, pattern := range policy.Columns {
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return dat.NewError("invalid redaction pattern " + policy.Columns[i])
+		}
+		columns[i] = pattern
+	}
+	db.redaction = &redaction{columns: columns, suppressArgs: policy.SuppressArgs}
+	return nil
+}
+
+// matchColumn returns true if the values of column must be masked.
+func (r *redaction) matchColumn(column string) bool {
+	column = strings.ToLower(column)
+	for _, pattern := range r.columns {
+		if ok, _ := path.Match(pattern, column); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply has b mask the values of the sensitive columns.
+func (r *redaction) apply(b dat.Builder) {
+	if r == nil || len(r.columns) == 0 {
+		return
+	}
+	if setter, ok := b.(dat.SecretColumnsSetter); ok {
+		setter.SetSecretColumns(r.matchColumn)
+	}
+}
+
+// args returns the arguments to log, nil if they are suppressed.
+func (r *redaction) args(args []interface{}) []interface{} {
+	if r != nil && r.suppressArgs {
+		return nil
+	}
+	return args
+}
+
+// statement is a statement to execute and its redacted form for logs and
+// errors.
+type statement struct {
+	sql  string
+	args []interface{}
+
+	logSQL  string
+	logArgs []interface{}
+
+	// fingerprint is the fingerprint of the SQL before interpolation, empty
+	// if statistics are disabled.
+	fingerprint string
+}
+
+// statement builds the statement of the builder, interpolating it if
+// enabled. When secret values were interpolated into the SQL, the logged
+// SQL and args are interpolated again with the values masked.
+func (ex *Execer) statement() (*statement, error) {
+	dialect := ex.sqlDialect()
+	if ex.timeout > 0 && !dialect.Supports(common.FeatureCancel) {
+		return nil, errTimeoutNotSupported
+	}
+	rawSQL, rawArgs, err := dat.PositionalSQL(ex.builder)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &statement{sql: rawSQL, args: rawArgs}
+	if ex.stats != nil {
+		st.fingerprint = Fingerprint(rawSQL)
+	}
+	if ex.builder.IsInterpolated() {
+		st.sql, st.args, err = dat.InterpolateWith(dialect, rawSQL, rawArgs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	st.logSQL = st.sql
+	st.logArgs = ex.redaction.args(st.args)
+	if st.sql != rawSQL {
+		if ex.redaction != nil && ex.redaction.suppressArgs {
+			st.logSQL = rawSQL
+		} else if hasSecret(rawArgs) {
+			st.logSQL, st.logArgs, err = dat.InterpolateWith(dialect, rawSQL, redactArgs(rawArgs))
+			if err != nil {
+				st.logSQL, st.logArgs = rawSQL, redactArgs(rawArgs)
+			}
+		}
+	}
+
+	st.sql, st.args, err = dialect.Rebind(st.sql, st.args)
+	if err != nil {
+		return nil, err
+	}
+	if logSQL, logArgs, err := dialect.Rebind(st.logSQL, st.logArgs); err == nil {
+		st.logSQL, st.logArgs = logSQL, logArgs
+	}
+
+	if ex.timeout > 0 {
+		st.sql = prependDatQueryID(st.sql, ex.queryID)
+		st.logSQL = prependDatQueryID(st.logSQL, ex.queryID)
+	}
+	return st, nil
+}
+
+func hasSecret(args []interface{}) bool {
+	for _, arg := range args {
+		if dat.IsSecret(arg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nerdynz/dat/dat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactionPolicyColumns(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{})
+	assert.NoError(t, db.SetRedactionPolicy(RedactionPolicy{Columns: []string{"EMAIL", "*_token"}}))
+
+	var id int64
+	err := db.InsertInto("people").
+		Columns("name", "email").
+		Values("Alice", "alice@acme.com").
+		Returning("id").
+		QueryScalar(&id)
+	assert.NoError(t, err)
+
+	var email string
+	err = db.Select("email").From("people").Where("id = $1", id).QueryScalar(&email)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@acme.com", email)
+
+	_, err = db.Update("people").Set("email", "bob@acme.com").Where("id = $1", id).Exec()
+	assert.NoError(t, err)
+
+	assert.NotContains(t, buf.String(), "alice@acme.com")
+	assert.NotContains(t, buf.String(), "bob@acme.com")
+	assert.Contains(t, buf.String(), "Alice")
+}
+
+func TestRedactionSecretInError(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{})
+
+	_, err := db.InsertInto("people").
+		Columns("id", "name", "email").
+		Values(1, "Dup", dat.Secret("hunter2")).
+		Exec()
+	var qe *dat.QueryError
+	if assert.True(t, errors.As(err, &qe)) {
+		assert.NotContains(t, qe.SQL, "hunter2")
+		assert.NotContains(t, qe.Error(), "hunter2")
+		for _, arg := range qe.Args {
+			assert.NotEqual(t, "hunter2", arg)
+		}
+	}
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestRedactionSecretJSONInterpolated(t *testing.T) {
+	dat.EnableInterpolation = true
+	defer func() { dat.EnableInterpolation = false }()
+	db, buf := newTestLogDB(LogOptions{})
+
+	var token string
+	err := db.SQL("SELECT $1::json->>'token'", dat.Secret(dat.JSON(`{"token":"hunter2"}`))).
+		QueryScalar(&token)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", token)
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestRedactionSuppressArgs(t *testing.T) {
+	installFixtures()
+	db, buf := newTestLogDB(LogOptions{})
+	assert.NoError(t, db.SetRedactionPolicy(RedactionPolicy{SuppressArgs: true}))
+
+	var name string
+	err := db.Select("name").From("people").Where("email = $1", "mario@acme.com").QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+
+	_, err = db.Exec("UPDATE people SET name = $1 WHERE email = $2", "Mario", "mario@acme.com")
+	assert.NoError(t, err)
+
+	assert.NotContains(t, buf.String(), "mario@acme.com")
+	for _, record := range logRecords(t, buf) {
+		assert.NotContains(t, record, "args")
+	}
+}
+
+func TestRedactionPolicyInvalidPattern(t *testing.T) {
+	db := newTestDB()
+	assert.Error(t, db.SetRedactionPolicy(RedactionPolicy{Columns: []string{"["}}))
+}
@@ -0,0 +1,250 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nerdynz/dat/dat"
+	"github.com/nerdynz/dat/internal/log"
+)
+
+// ReplicaPolicy selects the replica a read is routed to.
+type ReplicaPolicy int
+
+const (
+	// RoundRobin routes reads to the healthy replicas in turn.
+	RoundRobin ReplicaPolicy = iota
+	// LeastLatency routes reads to the healthy replica which answered the
+	// last health checks the fastest.
+	LeastLatency
+)
+
+// ReplicaOptions are the options of SetReplicas.
+type ReplicaOptions struct {
+	Policy ReplicaPolicy
+
+	// HealthCheckInterval is the interval between pings of each replica.
+	// Defaults to 5s, a negative value disables health checks.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout is the time a ping may take before the replica is
+	// unhealthy. Defaults to 1s.
+	HealthCheckTimeout time.Duration
+}
+
+// ReplicaStatus is the state of a replica.
+type ReplicaStatus struct {
+	DB      *sqlx.DB
+	Healthy bool
+	// Latency is the moving average of the health check pings.
+	Latency time.Duration
+	// Err is the error of the last failed health check, cleared once the
+	// replica is healthy again.
+	Err error
+}
+
+type replica struct {
+	db      *sqlx.DB
+	healthy atomic.Bool
+	latency atomic.Int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// replicaSet routes reads to the replicas of a DB.
+type replicaSet struct {
+	replicas []*replica
+	opts     ReplicaOptions
+	next     atomic.Uint64
+	stop     chan struct{}
+}
+
+// SetReplicas routes the reads of this DB to replicas. Select and
+// SelectDoc builders created from the DB run on the replica which is
+// healthy when they are executed, or on the primary if none is. Everything
+// else, including SELECT ... FOR UPDATE and all statements of a Tx, runs on
+// the primary. Use OnPrimary to read from the primary.
+//
+// Calling SetReplicas again replaces the replicas, SetReplicas(nil, ...)
+// routes all statements to the primary. The replicas are not closed. It is
+// safe to call SetReplicas while statements run.
+func (db *DB) SetReplicas(replicas []*sqlx.DB, opts ReplicaOptions) {
+	if len(replicas) == 0 {
+		db.swapReplicas(nil)
+		return
+	}
+
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = 5 * time.Second
+	}
+	if opts.HealthCheckTimeout <= 0 {
+		opts.HealthCheckTimeout = time.Second
+	}
+
+	set := &replicaSet{opts: opts, stop: make(chan struct{})}
+	for _, sqlxDB := range replicas {
+		r := &replica{db: sqlxDB}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+	if opts.HealthCheckInterval > 0 {
+		go set.healthCheck()
+	}
+	db.swapReplicas(set)
+}
+
+// swapReplicas replaces the replicas by set, stopping the health checks of
+// the previous replicas.
+func (db *DB) swapReplicas(set *replicaSet) {
+	if old := db.replicas.Swap(set); old != nil {
+		close(old.stop)
+	}
+}
+
+// Replicas returns the state of the replicas.
+func (db *DB) Replicas() []ReplicaStatus {
+	set := db.replicas.Load()
+	if set == nil {
+		return nil
+	}
+	statuses := make([]ReplicaStatus, len(set.replicas))
+	for i, r := range set.replicas {
+		r.mu.Lock()
+		statuses[i] = ReplicaStatus{
+			DB:      r.db,
+			Healthy: r.healthy.Load(),
+			Latency: time.Duration(r.latency.Load()),
+			Err:     r.err,
+		}
+		r.mu.Unlock()
+	}
+	return statuses
+}
+
+// CheckReplicas pings the replicas now, updating their health.
+func (db *DB) CheckReplicas() {
+	if set := db.replicas.Load(); set != nil {
+		set.check()
+	}
+}
+
+// pick returns a healthy replica according to the policy, or nil.
+func (s *replicaSet) pick() *sqlx.DB {
+	if s == nil {
+		return nil
+	}
+
+	switch s.opts.Policy {
+	case LeastLatency:
+		var best *replica
+		for _, r := range s.replicas {
+			if r.healthy.Load() && (best == nil || r.latency.Load() < best.latency.Load()) {
+				best = r
+			}
+		}
+		if best != nil {
+			return best.db
+		}
+	default:
+		n := uint64(len(s.replicas))
+		start := s.next.Add(1)
+		for i := uint64(0); i < n; i++ {
+			r := s.replicas[(start+i)%n]
+			if r.healthy.Load() {
+				return r.db
+			}
+		}
+	}
+	return nil
+}
+
+func (s *replicaSet) healthCheck() {
+	ticker := time.NewTicker(s.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+func (s *replicaSet) check() {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.check(s.opts.HealthCheckTimeout)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (r *replica) check(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := r.db.PingContext(ctx)
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if r.healthy.Swap(false) {
+			log.Error("replica is unhealthy", "err", err)
+		}
+		r.err = err
+		return
+	}
+
+	if !r.healthy.Swap(true) {
+		log.Debug("replica is healthy again")
+	}
+	r.err = nil
+	if latency := r.latency.Load(); latency == 0 {
+		r.latency.Store(int64(elapsed))
+	} else {
+		// exponential moving average, so one slow ping does not reroute
+		r.latency.Store((latency*4 + int64(elapsed)) / 5)
+	}
+}
+
+// isReadBuilder returns true if b only reads. Selects which lock rows with
+// a FOR clause must run on the primary.
+func isReadBuilder(b dat.Builder) bool {
+	switch b := b.(type) {
+	case *dat.SelectBuilder:
+		return !b.IsLocking()
+	case *dat.SelectDocBuilder:
+		return !b.IsLocking()
+	}
+	return false
+}
+
+// route picks the database the builder's statement runs on when it is
+// executed, so a replica which became unhealthy since the builder was
+// created is skipped. Cancel runs on the same database.
+func (ex *Execer) route() {
+	ex.database = ex.primary
+	if ex.replicas == nil || !isReadBuilder(ex.builder) {
+		return
+	}
+	if replica := ex.replicas.Load().pick(); replica != nil {
+		ex.database = replica
+	}
+}
+
+// OnPrimary runs the builder's statement on the primary, even when it is a
+// read routed to a replica.
+func (ex *Execer) OnPrimary() dat.Execer {
+	ex.replicas = nil
+	return ex
+}
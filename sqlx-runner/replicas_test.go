@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReplicaDB(opts ReplicaOptions) (*DB, *sqlx.DB, *sqlx.DB) {
+	db := newTestDB()
+	healthy := sqlx.NewDb(sqlDB, "postgres")
+	down := newDeadDB()
+	opts.HealthCheckInterval = -1
+	db.SetReplicas([]*sqlx.DB{down, healthy}, opts)
+	return db, healthy, down
+}
+
+func TestReplicaRouting(t *testing.T) {
+	installFixtures()
+	db, healthy, down := newTestReplicaDB(ReplicaOptions{})
+	defer db.SetReplicas(nil, ReplicaOptions{})
+
+	db.CheckReplicas()
+	statuses := db.Replicas()
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, down, statuses[0].DB)
+		assert.False(t, statuses[0].Healthy)
+		assert.Error(t, statuses[0].Err)
+		assert.True(t, statuses[1].Healthy)
+		assert.True(t, statuses[1].Latency > 0)
+	}
+
+	for i := 0; i < 3; i++ {
+		ex := db.Select("name").From("people").Execer.(*Execer)
+		ex.route()
+		assert.Equal(t, healthy, ex.database)
+	}
+
+	ex := db.Select("name").From("people").Execer.(*Execer)
+	ex.OnPrimary()
+	ex.route()
+	assert.Equal(t, testDB.DB, ex.database)
+
+	ex = db.Select("name").From("people").For("UPDATE").Execer.(*Execer)
+	ex.route()
+	assert.Equal(t, testDB.DB, ex.database)
+
+	ex = db.SelectDoc("name").From("people").For("SHARE").Execer.(*Execer)
+	ex.route()
+	assert.Equal(t, testDB.DB, ex.database)
+
+	ex = db.Update("people").Set("name", "x").Execer.(*Execer)
+	ex.route()
+	assert.Equal(t, testDB.DB, ex.database)
+
+	var name string
+	err := db.Select("name").From("people").Where("id = $1", 1).QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+}
+
+func TestReplicaTxUsesPrimary(t *testing.T) {
+	installFixtures()
+	db, _, _ := newTestReplicaDB(ReplicaOptions{Policy: LeastLatency})
+	defer db.SetReplicas(nil, ReplicaOptions{})
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	ex := tx.Select("name").From("people").Execer.(*Execer)
+	ex.route()
+	assert.Equal(t, tx.Tx, ex.database)
+}
+
+func TestReplicaFallbackToPrimary(t *testing.T) {
+	installFixtures()
+	db := newTestDB()
+	db.SetReplicas([]*sqlx.DB{newDeadDB()}, ReplicaOptions{HealthCheckInterval: -1})
+	defer db.SetReplicas(nil, ReplicaOptions{})
+
+	// the replica is picked when the statement runs, after the health
+	// check found the replica down
+	b := db.Select("name").From("people").Where("id = $1", 1)
+	db.CheckReplicas()
+
+	var name string
+	err := b.QueryScalar(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mario", name)
+	assert.Equal(t, testDB.DB, b.Execer.(*Execer).database)
+}
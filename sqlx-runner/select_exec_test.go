@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/nerdynz/dat/dat"
@@ -321,3 +322,25 @@ func TestSelectFor(t *testing.T) {
 }
 
 // Series of tests that test mapping struct fields to columns
+
+func TestSelectQueryError(t *testing.T) {
+	var people []Person
+	err := testDB.Select("id", "nope").From("people").Where("id > $1", 0).QueryStructs(&people)
+
+	var qe *dat.QueryError
+	if assert.True(t, errors.As(err, &qe)) {
+		assert.Equal(t, "Select", qe.Builder)
+		assert.Contains(t, qe.SQL, "nope")
+		assert.True(t, qe.Elapsed > 0)
+		if !dat.EnableInterpolation {
+			assert.Equal(t, []interface{}{0}, qe.Args)
+		}
+	}
+	assert.Equal(t, "42703", dat.SQLState(err))
+
+	_, err = testDB.Exec("SELECT nope FROM people")
+	if assert.True(t, errors.As(err, &qe)) {
+		assert.Equal(t, "Exec", qe.Builder)
+		assert.Equal(t, "SELECT nope FROM people", qe.SQL)
+	}
+}
@@ -126,3 +126,25 @@ $$ LANGUAGE plpgsql;
 	assert.NoError(t, err)
 	assert.Equal(t, "Hello world!", s)
 }
+
+func TestRegisterFunctionsInDir(t *testing.T) {
+	testDB.MustCreateMetaTable()
+	err := testDB.RegisterFunctionsInDir("fixtures", "1")
+	assert.NoError(t, err)
+	// registering the same version again is a noop
+	err = testDB.RegisterFunctionsInDir("fixtures", "1")
+	assert.NoError(t, err)
+
+	var s string
+	err = testDB.SQL(`select add()`).QueryScalar(&s)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world!", s)
+
+	var n int
+	err = testDB.SQL(`SELECT count(*) FROM dat__meta WHERE kind = 'function' AND name IN ('add', 'subtract')`).QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.True(t, n >= 2)
+
+	err = testDB.RegisterFunctionsInDir("does-not-exist", "1")
+	assert.Error(t, err)
+}
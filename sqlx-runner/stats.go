@@ -0,0 +1,264 @@
+package runner
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// QueryStatsOptions configures the statement statistics of a DB.
+type QueryStatsOptions struct {
+	// MaxFingerprints caps the number of fingerprints tracked. Statements
+	// with new fingerprints past the cap are not recorded. Defaults to 1000.
+	MaxFingerprints int
+
+	// Samples is the number of recent latencies kept per fingerprint to
+	// compute P95. Defaults to 512.
+	Samples int
+}
+
+// QueryStat are the statistics of the statements sharing a fingerprint.
+type QueryStat struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       int64         `json:"count"`
+	Errors      int64         `json:"errors"`
+	Rows        int64         `json:"rows"`
+	TotalTime   time.Duration `json:"total_time"`
+	MaxTime     time.Duration `json:"max_time"`
+	// P95Time is the 95th percentile of the most recent latencies.
+	P95Time time.Duration `json:"p95_time"`
+}
+
+// MeanTime is the average latency.
+func (s *QueryStat) MeanTime() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Count)
+}
+
+// queryStats aggregates the statistics of the statements of a DB by
+// fingerprint.
+type queryStats struct {
+	sync.Mutex
+	opts  QueryStatsOptions
+	stats map[string]*queryStat
+}
+
+type queryStat struct {
+	QueryStat
+	samples []time.Duration
+	next    int
+}
+
+// EnableQueryStats starts aggregating statistics of the statements run on
+// this DB and by any Tx begun from it afterwards. Statements are grouped by
+// their fingerprint, see Fingerprint. Calling it again resets the
+// statistics.
+func (db *DB) EnableQueryStats(opts QueryStatsOptions) {
+	if opts.MaxFingerprints <= 0 {
+		opts.MaxFingerprints = 1000
+	}
+	if opts.Samples <= 0 {
+		opts.Samples = 512
+	}
+	db.stats = &queryStats{opts: opts, stats: map[string]*queryStat{}}
+}
+
+// QueryStats returns the statistics of the statements by fingerprint, by
+// descending total time. It returns nil if statistics are not enabled.
+func (db *DB) QueryStats() []QueryStat {
+	return db.stats.snapshot()
+}
+
+// ResetQueryStats clears the statistics.
+func (db *DB) ResetQueryStats() {
+	s := db.stats
+	if s == nil {
+		return
+	}
+	s.Lock()
+	s.stats = map[string]*queryStat{}
+	s.Unlock()
+}
+
+// record records a statement. rows is -1 if unknown.
+func (s *queryStats) record(fingerprint string, elapsed time.Duration, rows int64, failed bool) {
+	if s == nil || fingerprint == "" {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	stat := s.stats[fingerprint]
+	if stat == nil {
+		if len(s.stats) >= s.opts.MaxFingerprints {
+			return
+		}
+		stat = &queryStat{QueryStat: QueryStat{Fingerprint: fingerprint}}
+		s.stats[fingerprint] = stat
+	}
+
+	stat.Count++
+	if failed {
+		stat.Errors++
+	}
+	if rows > 0 {
+		stat.Rows += rows
+	}
+	stat.TotalTime += elapsed
+	if elapsed > stat.MaxTime {
+		stat.MaxTime = elapsed
+	}
+	if len(stat.samples) < s.opts.Samples {
+		stat.samples = append(stat.samples, elapsed)
+	} else {
+		stat.samples[stat.next] = elapsed
+		stat.next = (stat.next + 1) % len(stat.samples)
+	}
+}
+
+func (s *queryStats) snapshot() []QueryStat {
+	if s == nil {
+		return nil
+	}
+
+	s.Lock()
+	result := make([]QueryStat, 0, len(s.stats))
+	for _, stat := range s.stats {
+		qs := stat.QueryStat
+		qs.P95Time = percentile(stat.samples, 0.95)
+		result = append(result, qs)
+	}
+	s.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalTime != result[j].TotalTime {
+			return result[i].TotalTime > result[j].TotalTime
+		}
+		return result[i].Fingerprint < result[j].Fingerprint
+	})
+	return result
+}
+
+// percentile returns the p-th percentile of samples using the nearest rank.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	} else if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+var (
+	valueListRe = regexp.MustCompile(`\?(\s*,\s*\?)+`)
+	rowListRe   = regexp.MustCompile(`\(\?\)(\s*,\s*\(\?\))+`)
+)
+
+// Fingerprint normalizes a statement so statements differing only in their
+// values share a fingerprint. Comments are removed, whitespace is collapsed,
+// literals and placeholders are replaced by ? and lists of them by a single
+// ?, so
+//
+//	SELECT * FROM t WHERE id IN ($1,$2,$3) AND kind = 'a'
+//
+// becomes
+//
+//	SELECT * FROM t WHERE id IN (?) AND kind = ?
+func Fingerprint(sql string) string {
+	var buf bytes.Buffer
+	buf.Grow(len(sql))
+	space := false
+	write := func(s string) {
+		if space && buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		space = false
+		buf.WriteString(s)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+			space = true
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			space = true
+		case unicode.IsSpace(rune(c)):
+			i++
+			space = true
+		case c == '\'':
+			// '' escapes a quote
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+			write("?")
+		case c == '$' && i+1 < len(sql) && isDigit(sql[i+1]):
+			for i++; i < len(sql) && isDigit(sql[i]); i++ {
+			}
+			write("?")
+		case isDigit(c):
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+				i++
+			}
+			write("?")
+		case c == '"':
+			end := strings.IndexByte(sql[i+1:], '"')
+			if end < 0 {
+				end = len(sql) - i - 2
+			}
+			write(sql[i : i+end+2])
+			i += end + 2
+		case isIdentifier(c):
+			start := i
+			for i < len(sql) && (isIdentifier(sql[i]) || isDigit(sql[i]) || sql[i] == '$') {
+				i++
+			}
+			write(sql[start:i])
+		default:
+			write(sql[i : i+1])
+			i++
+		}
+	}
+
+	fingerprint := valueListRe.ReplaceAllString(buf.String(), "?")
+	return rowListRe.ReplaceAllString(fingerprint, "(?)")
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
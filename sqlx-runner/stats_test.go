@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM t WHERE id IN ($1,$2,$3) AND kind = 'a'":                      "SELECT * FROM t WHERE id IN (?) AND kind = ?",
+		"INSERT INTO people (name,email) VALUES ($1,$2),($3,$4)\nRETURNING id":       "INSERT INTO people (name,email) VALUES (?) RETURNING id",
+		"--dat:qid=x\nSELECT  a1, \"B 2\" FROM t /* c */ WHERE x = 'it''s' LIMIT 10": "SELECT a1, \"B 2\" FROM t WHERE x = ? LIMIT ?",
+		"SELECT $1::int, 3.5": "SELECT ?::int, ?",
+	}
+	for sql, expected := range cases {
+		assert.Equal(t, expected, Fingerprint(sql), sql)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(95), percentile(samples, 0.95))
+	assert.Equal(t, time.Duration(100), samples[0], "samples are not sorted in place")
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.95))
+}
+
+func TestQueryStats(t *testing.T) {
+	installFixtures()
+	db := newTestDB()
+	assert.Nil(t, db.QueryStats())
+	db.EnableQueryStats(QueryStatsOptions{})
+
+	for _, id := range []int{1, 2, 3} {
+		var name string
+		err := db.Select("name").From("people").Where("id = $1", id).QueryScalar(&name)
+		assert.NoError(t, err)
+	}
+	var name string
+	err := db.Select("name").From("people").Where("id = $1", 1000).QueryScalar(&name)
+	assert.Error(t, err)
+	_, err = db.Exec("SELECT nope FROM people WHERE id = 1")
+	assert.Error(t, err)
+
+	stats := db.QueryStats()
+	byFingerprint := map[string]QueryStat{}
+	for _, s := range stats {
+		byFingerprint[s.Fingerprint] = s
+	}
+
+	selectStat := byFingerprint["SELECT name FROM people WHERE (id = ?)"]
+	assert.EqualValues(t, 4, selectStat.Count)
+	assert.EqualValues(t, 0, selectStat.Errors)
+	assert.EqualValues(t, 3, selectStat.Rows)
+	assert.True(t, selectStat.MaxTime > 0)
+	assert.True(t, selectStat.P95Time <= selectStat.MaxTime)
+	assert.True(t, selectStat.MeanTime() <= selectStat.MaxTime)
+
+	execStat := byFingerprint["SELECT nope FROM people WHERE id = ?"]
+	assert.EqualValues(t, 1, execStat.Count)
+	assert.EqualValues(t, 1, execStat.Errors)
+
+	db.ResetQueryStats()
+	assert.Empty(t, db.QueryStats())
+}
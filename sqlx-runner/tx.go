@@ -1,10 +1,13 @@
 package runner
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"strconv"
 	"sync"
-	"time"
 
+	"github.com/nerdynz/dat/common"
 	"github.com/nerdynz/dat/dat"
 	"github.com/nerdynz/dat/internal/log"
 	"github.com/jmoiron/sqlx"
@@ -28,37 +31,96 @@ type Tx struct {
 	*sqlx.Tx
 	*Queryable
 	IsRollbacked bool
+	id           uint64
 	state        int
 	stateStack   []int
+	options      TxOptions
+
+	// callbacks are registered at the current nesting level, callbackStack
+	// holds those of the outer levels
+	callbacks     txCallbacks
+	callbackStack []txCallbacks
 }
 
-// WrapSqlxTx creates a Tx from a sqlx.Tx
+// WrapSqlxTx creates a Tx from a sqlx.Tx. The Tx is watched for leaks, see
+// SetTxWatchdog.
 func WrapSqlxTx(tx *sqlx.Tx) *Tx {
-	newtx := &Tx{Tx: tx, Queryable: &Queryable{tx}}
-	if dat.Strict {
-		time.AfterFunc(1*time.Minute, func() {
-			if !newtx.IsRollbacked && newtx.state == txPending {
-				panic("A database transaction was not closed!")
-			}
-		})
-	}
+	// an unknown driver writes in dat.Dialect
+	dialect, _ := dialectFor(tx.DriverName())
+	newtx := &Tx{Tx: tx, Queryable: &Queryable{runner: tx, dialect: dialect}}
+	watchdog.track(newtx)
 	return newtx
 }
 
+// TxOptions are the options a transaction is begun with.
+type TxOptions struct {
+	// Isolation is the isolation level, sql.LevelDefault uses the server's
+	// default_transaction_isolation.
+	Isolation sql.IsolationLevel
+
+	// ReadOnly begins a READ ONLY transaction.
+	ReadOnly bool
+
+	// Deferrable begins a DEFERRABLE transaction. It only has an effect on
+	// SERIALIZABLE READ ONLY transactions, which then wait for a snapshot
+	// that cannot fail with a serialization failure. Only Postgres supports
+	// it, BeginWith returns an error on other databases.
+	Deferrable bool
+}
+
 // Begin creates a transaction for the given database
 func (db *DB) Begin() (*Tx, error) {
-	tx, err := db.DB.Beginx()
+	return db.BeginWith(TxOptions{})
+}
+
+// BeginWith creates a transaction with isolation level and access mode set
+// by opts.
+func (db *DB) BeginWith(opts TxOptions) (*Tx, error) {
+	if opts.Deferrable && !db.Dialect().Supports(common.FeatureDeferrable) {
+		return nil, dat.NewError("Deferrable is not supported by the dialect")
+	}
+	tx, err := db.DB.BeginTxx(context.Background(), &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	})
 	if err != nil {
-		if dat.Strict {
-			log.Fatal("Could not create transaction")
+		return nil, logTxError("begin.error", err)
+	}
+	if opts.Deferrable {
+		// database/sql has no notion of DEFERRABLE, it must be set before
+		// the first query of the transaction
+		if _, err = tx.Exec("SET TRANSACTION DEFERRABLE"); err != nil {
+			tx.Rollback()
+			return nil, logTxError("begin.deferrable_error", err)
 		}
-		return nil, log.ErrorE("begin.error", err)
 	}
-	log.Debug("begin tx")
-	return WrapSqlxTx(tx), nil
+	log.Debug("begin tx", "isolation", opts.Isolation, "readOnly", opts.ReadOnly, "deferrable", opts.Deferrable)
+	newtx := WrapSqlxTx(tx)
+	newtx.cache = db.cache
+	newtx.logger = db.logger.withTx(newtx.id)
+	newtx.redaction = db.redaction
+	newtx.stats = db.stats
+	newtx.counters = db.counters
+	newtx.dialect = db.dialect
+	newtx.counters.txBegun()
+	newtx.options = opts
+	return newtx, nil
+}
+
+// ID returns the identifier of the transaction, unique within the process.
+func (tx *Tx) ID() uint64 {
+	return tx.id
 }
 
-// Begin returns this transaction
+// Options returns the options this transaction was begun with. Nested
+// transactions share the options of the outermost transaction.
+func (tx *Tx) Options() TxOptions {
+	return tx.options
+}
+
+// Begin starts a nested transaction by creating a savepoint. A nested Commit
+// releases the savepoint and a nested Rollback rolls back to it, leaving the
+// outer transaction intact.
 func (tx *Tx) Begin() (*Tx, error) {
 	tx.Lock()
 	defer tx.Unlock()
@@ -68,11 +130,18 @@ func (tx *Tx) Begin() (*Tx, error) {
 
 	log.Debug("begin nested tx")
 	tx.pushState()
+	_, err := tx.Tx.Exec("SAVEPOINT " + tx.savepoint())
+	if err != nil {
+		tx.popState()
+		return nil, logTxError("begin.savepoint_error", err)
+	}
 	return tx, nil
 }
 
 // Commit commits the transaction
 func (tx *Tx) Commit() error {
+	var fire []func()
+	defer func() { runTxCallbacks(fire) }()
 	tx.Lock()
 	defer tx.Unlock()
 
@@ -88,13 +157,20 @@ func (tx *Tx) Commit() error {
 		return log.ErrorE("Transaction has already been rollbacked")
 	}
 
-	if len(tx.stateStack) == 0 {
+	if tx.isNested() {
+		err := tx.releaseSavepoint()
+		if err != nil {
+			return err
+		}
+	} else {
 		err := tx.Tx.Commit()
+		tx.untrack()
 		if err != nil {
 			tx.state = txErred
-			log.Error("commit.error", err)
-			return err
+			fire = tx.takeRollbackCallbacks()
+			return logTxError("commit.error", err)
 		}
+		fire = tx.takeCommitCallbacks()
 	}
 
 	log.Debug("commit")
@@ -104,6 +180,8 @@ func (tx *Tx) Commit() error {
 
 // Rollback cancels the transaction
 func (tx *Tx) Rollback() error {
+	var fire []func()
+	defer func() { runTxCallbacks(fire) }()
 	tx.Lock()
 	defer tx.Unlock()
 
@@ -114,12 +192,27 @@ func (tx *Tx) Rollback() error {
 	if tx.state == txCommitted {
 		return log.ErrorE("Cannot rollback, transaction has already been commited")
 	}
+	if tx.state == txRollbacked {
+		return log.ErrorE("Transaction has already been rollbacked")
+	}
+
+	if tx.isNested() {
+		err := tx.rollbackToSavepoint()
+		if err != nil {
+			return err
+		}
+		log.Debug("rollback nested")
+		tx.state = txRollbacked
+		fire = tx.takeRollbackCallbacks()
+		return nil
+	}
 
-	// rollback is sent to the database even in nested state
 	err := tx.Tx.Rollback()
+	tx.untrack()
+	fire = tx.takeRollbackCallbacks()
 	if err != nil {
 		tx.state = txErred
-		return log.ErrorE("Unable to rollback", "err", err)
+		return logTxError("Unable to rollback", err)
 	}
 
 	log.Debug("rollback")
@@ -130,47 +223,65 @@ func (tx *Tx) Rollback() error {
 
 // AutoCommit commits a transaction IF neither Commit or Rollback were called.
 func (tx *Tx) AutoCommit() error {
+	var fire []func()
+	defer func() { runTxCallbacks(fire) }()
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.state == txRollbacked || tx.IsRollbacked {
+	if tx.state != txPending || tx.IsRollbacked {
 		tx.popState()
 		return nil
 	}
 
+	if tx.isNested() {
+		err := tx.releaseSavepoint()
+		tx.popState()
+		return err
+	}
+
 	err := tx.Tx.Commit()
+	tx.untrack()
 	if err != nil {
 		tx.state = txErred
-		if dat.Strict {
-			log.Fatal("Could not commit transaction", err.Error())
-		}
+		fire = tx.takeRollbackCallbacks()
 		tx.popState()
-		return log.ErrorE("transaction.AutoCommit.commit_error", err)
+		return logTxError("transaction.AutoCommit.commit_error", err)
 	}
 	log.Debug("autocommit")
 	tx.state = txCommitted
+	fire = tx.takeCommitCallbacks()
 	tx.popState()
 	return err
 }
 
 // AutoRollback rolls back transaction IF neither Commit or Rollback were called.
 func (tx *Tx) AutoRollback() error {
+	var fire []func()
+	defer func() { runTxCallbacks(fire) }()
 	tx.Lock()
 	defer tx.Unlock()
 
-	if tx.IsRollbacked || tx.state == txCommitted {
+	if tx.IsRollbacked || tx.state != txPending {
 		tx.popState()
 		return nil
 	}
 
+	if tx.isNested() {
+		err := tx.rollbackToSavepoint()
+		if err == nil {
+			fire = tx.takeRollbackCallbacks()
+		}
+		tx.popState()
+		return err
+	}
+
 	err := tx.Tx.Rollback()
+	tx.untrack()
+	fire = tx.takeRollbackCallbacks()
 	if err != nil {
 		tx.state = txErred
-		if dat.Strict {
-			log.Fatal("Could not rollback transaction", err.Error())
-		}
 		tx.popState()
-		return log.ErrorE("transaction.AutoRollback.rollback_error", err)
+		return logTxError("transaction.AutoRollback.rollback_error", err)
 	}
 	log.Debug("autorollback")
 	tx.state = txRollbacked
@@ -188,6 +299,8 @@ func (tx *Tx) Select(columns ...string) *dat.SelectBuilder {
 func (tx *Tx) pushState() {
 	tx.stateStack = append(tx.stateStack, tx.state)
 	tx.state = txPending
+	tx.callbackStack = append(tx.callbackStack, tx.callbacks)
+	tx.callbacks = txCallbacks{}
 }
 
 func (tx *Tx) popState() {
@@ -198,4 +311,44 @@ func (tx *Tx) popState() {
 	var val int
 	val, tx.stateStack = tx.stateStack[len(tx.stateStack)-1], tx.stateStack[:len(tx.stateStack)-1]
 	tx.state = val
+
+	// callbacks of a released savepoint are decided by the outer transaction
+	var callbacks txCallbacks
+	callbacks, tx.callbackStack = tx.callbackStack[len(tx.callbackStack)-1], tx.callbackStack[:len(tx.callbackStack)-1]
+	callbacks.onCommit = append(callbacks.onCommit, tx.callbacks.onCommit...)
+	callbacks.onRollback = append(callbacks.onRollback, tx.callbacks.onRollback...)
+	tx.callbacks = callbacks
+}
+
+func (tx *Tx) isNested() bool {
+	return len(tx.stateStack) > 0
+}
+
+// savepoint is the name of the savepoint for the current nesting level.
+func (tx *Tx) savepoint() string {
+	return "dat_sp_" + strconv.Itoa(len(tx.stateStack))
+}
+
+func (tx *Tx) releaseSavepoint() error {
+	_, err := tx.Tx.Exec("RELEASE SAVEPOINT " + tx.savepoint())
+	if err != nil {
+		tx.state = txErred
+		return logTxError("commit.release_savepoint_error", err)
+	}
+	return nil
+}
+
+func (tx *Tx) rollbackToSavepoint() error {
+	_, err := tx.Tx.Exec("ROLLBACK TO SAVEPOINT " + tx.savepoint())
+	if err != nil {
+		tx.state = txErred
+		return logTxError("rollback.savepoint_error", err)
+	}
+	return nil
+}
+
+// logTxError logs err and returns it wrapped in a dat.SQLError.
+func logTxError(msg string, err error) error {
+	log.Error(msg, "err", err)
+	return dat.WrapSQLError(msg, err)
 }
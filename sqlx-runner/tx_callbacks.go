@@ -0,0 +1,50 @@
+package runner
+
+// txCallbacks are the callbacks registered at one nesting level of a Tx.
+type txCallbacks struct {
+	onCommit   []func()
+	onRollback []func()
+}
+
+// OnCommit registers fn to be called once the outermost transaction has
+// committed. If fn is registered in a nested transaction which is rolled
+// back, fn is never called.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.Lock()
+	defer tx.Unlock()
+	tx.callbacks.onCommit = append(tx.callbacks.onCommit, fn)
+}
+
+// OnRollback registers fn to be called once the work done since fn was
+// registered is rolled back, either by the outermost transaction or by the
+// nested transaction fn was registered in. fn is also called if the commit
+// fails.
+func (tx *Tx) OnRollback(fn func()) {
+	tx.Lock()
+	defer tx.Unlock()
+	tx.callbacks.onRollback = append(tx.callbacks.onRollback, fn)
+}
+
+// takeCommitCallbacks returns the commit callbacks of the current level and
+// discards all callbacks of the level. The caller must hold the lock.
+func (tx *Tx) takeCommitCallbacks() []func() {
+	fns := tx.callbacks.onCommit
+	tx.callbacks = txCallbacks{}
+	return fns
+}
+
+// takeRollbackCallbacks returns the rollback callbacks of the current level
+// and discards all callbacks of the level. The caller must hold the lock.
+func (tx *Tx) takeRollbackCallbacks() []func() {
+	fns := tx.callbacks.onRollback
+	tx.callbacks = txCallbacks{}
+	return fns
+}
+
+// runTxCallbacks calls fns in the order they were registered. It is called
+// after the lock is released so callbacks may use the connection.
+func runTxCallbacks(fns []func()) {
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -3,8 +3,12 @@ package runner
 import (
 	// "database/sql"
 	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -202,8 +206,9 @@ func TestRollbackWithNestedRollback(t *testing.T) {
 	assert.NoError(t, err)
 	err = nestedRollback(tx)
 	assert.NoError(t, err)
+	assert.False(t, tx.IsRollbacked)
 	err = tx.Rollback()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -221,7 +226,7 @@ func TestCommitWithNestedRollback(t *testing.T) {
 	err = nestedRollback(tx)
 	assert.NoError(t, err)
 	err = tx.Commit()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -239,7 +244,7 @@ func TestCommitWithNestedNestedRollback(t *testing.T) {
 	err = nestedNestedRollback(tx)
 	assert.NoError(t, err)
 	err = tx.Commit()
-	assert.Exactly(t, ErrTxRollbacked, err)
+	assert.NoError(t, err)
 
 	var person Person
 	err = testDB.
@@ -250,6 +255,58 @@ func TestCommitWithNestedNestedRollback(t *testing.T) {
 	assert.Exactly(t, sql.ErrNoRows, err)
 }
 
+func TestCommitKeepsWorkOutsideNestedRollback(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	_, err = tx.InsertInto("people").Columns("name", "email").
+		Values("Luigi", "luigi@mgutz.com").
+		Exec()
+	assert.NoError(t, err)
+	err = nestedRollback(tx)
+	assert.NoError(t, err)
+	err = tx.Commit()
+	assert.NoError(t, err)
+
+	var person Person
+	err = testDB.
+		Select("*").
+		From("people").
+		Where("email = $1", "luigi@mgutz.com").
+		QueryStruct(&person)
+	assert.NoError(t, err)
+	assert.True(t, person.ID > 0)
+
+	err = testDB.
+		Select("*").
+		From("people").
+		Where("email = $1", "mario@mgutz.com").
+		QueryStruct(&person)
+	assert.Exactly(t, sql.ErrNoRows, err)
+}
+
+func TestNestedRollbackExplicit(t *testing.T) {
+	installFixtures()
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+
+	nested, err := tx.Begin()
+	assert.NoError(t, err)
+	_, err = nested.InsertInto("people").Columns("name", "email").
+		Values("Mario", "mario@mgutz.com").
+		Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, nested.Rollback())
+	assert.NoError(t, nested.AutoRollback())
+
+	var n int
+	err = tx.SQL("SELECT count(*) FROM people WHERE email = $1", "mario@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, tx.Commit())
+}
+
 func TestErrorInBeginIfRollbacked(t *testing.T) {
 	installFixtures()
 	tx, err := testDB.Begin()
@@ -260,3 +317,210 @@ func TestErrorInBeginIfRollbacked(t *testing.T) {
 	_, err = tx.Begin()
 	assert.Exactly(t, ErrTxRollbacked, err)
 }
+
+func TestInTxCommit(t *testing.T) {
+	installFixtures()
+	err := testDB.InTx(func(tx *Tx) error {
+		_, err := tx.InsertInto("people").Columns("name", "email").
+			Values("Mario", "mario@mgutz.com").
+			Exec()
+		return err
+	}, nil)
+	assert.NoError(t, err)
+
+	var n int
+	err = testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "mario@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestInTxRollbackOnError(t *testing.T) {
+	installFixtures()
+	errAbort := errors.New("abort")
+	err := testDB.InTx(func(tx *Tx) error {
+		_, err := tx.InsertInto("people").Columns("name", "email").
+			Values("Mario", "mario@mgutz.com").
+			Exec()
+		assert.NoError(t, err)
+		return errAbort
+	}, nil)
+	assert.Exactly(t, errAbort, err)
+
+	var n int
+	err = testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "mario@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestInTxRollbackOnPanic(t *testing.T) {
+	installFixtures()
+	assert.Panics(t, func() {
+		testDB.InTx(func(tx *Tx) error {
+			_, err := tx.InsertInto("people").Columns("name", "email").
+				Values("Mario", "mario@mgutz.com").
+				Exec()
+			assert.NoError(t, err)
+			panic("boom")
+		}, nil)
+	})
+
+	var n int
+	err := testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "mario@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestInTxRetry(t *testing.T) {
+	installFixtures()
+	attempts := 0
+	err := testDB.InTx(func(tx *Tx) error {
+		attempts++
+		_, err := tx.InsertInto("people").Columns("name", "email").
+			Values("Mario", "mario@mgutz.com").
+			Exec()
+		assert.NoError(t, err)
+		if attempts < 3 {
+			return fmt.Errorf("wrapped: %w", &pq.Error{Code: "40001"})
+		}
+		return nil
+	}, &InTxOptions{InitialInterval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	var n int
+	err = testDB.SQL("SELECT count(*) FROM people WHERE email = $1", "mario@mgutz.com").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	attempts = 0
+	err = testDB.InTx(func(tx *Tx) error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	}, &InTxOptions{MaxRetries: -1})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestBeginWith(t *testing.T) {
+	installFixtures()
+	opts := TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true}
+	tx, err := testDB.BeginWith(opts)
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	assert.Equal(t, opts, tx.Options())
+
+	var setting string
+	err = tx.SQL("SHOW transaction_isolation").QueryScalar(&setting)
+	assert.NoError(t, err)
+	assert.Equal(t, "serializable", setting)
+	err = tx.SQL("SHOW transaction_read_only").QueryScalar(&setting)
+	assert.NoError(t, err)
+	assert.Equal(t, "on", setting)
+	err = tx.SQL("SHOW transaction_deferrable").QueryScalar(&setting)
+	assert.NoError(t, err)
+	assert.Equal(t, "on", setting)
+
+	var n int
+	err = tx.Select("count(*)").From("people").QueryScalar(&n)
+	assert.NoError(t, err)
+	assert.True(t, n > 0)
+
+	_, err = tx.InsertInto("people").Columns("name", "email").
+		Values("Mario", "mario@mgutz.com").
+		Exec()
+	assert.Error(t, err)
+}
+
+func TestTxCallbacksCommit(t *testing.T) {
+	installFixtures()
+	var calls []string
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	defer tx.AutoRollback()
+	tx.OnCommit(func() { calls = append(calls, "commit") })
+	tx.OnRollback(func() { calls = append(calls, "rollback") })
+
+	nested, err := tx.Begin()
+	assert.NoError(t, err)
+	nested.OnCommit(func() { calls = append(calls, "nested commit") })
+	assert.NoError(t, nested.Commit())
+	assert.NoError(t, nested.AutoRollback())
+	assert.Empty(t, calls)
+
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"commit", "nested commit"}, calls)
+	assert.NoError(t, tx.AutoRollback())
+	assert.Equal(t, []string{"commit", "nested commit"}, calls)
+}
+
+func TestTxCallbacksRollback(t *testing.T) {
+	installFixtures()
+	var calls []string
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	tx.OnCommit(func() { calls = append(calls, "commit") })
+	tx.OnRollback(func() { calls = append(calls, "rollback") })
+
+	assert.NoError(t, tx.AutoRollback())
+	assert.Equal(t, []string{"rollback"}, calls)
+}
+
+func TestTxCallbacksNestedRollback(t *testing.T) {
+	installFixtures()
+	var calls []string
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+	tx.OnCommit(func() { calls = append(calls, "commit") })
+
+	err = func() error {
+		nested, err := tx.Begin()
+		if err != nil {
+			return err
+		}
+		defer nested.AutoRollback()
+		nested.OnCommit(func() { calls = append(calls, "nested commit") })
+		nested.OnRollback(func() { calls = append(calls, "nested rollback") })
+		return nil
+	}()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nested rollback"}, calls)
+
+	assert.NoError(t, tx.AutoCommit())
+	assert.Equal(t, []string{"nested rollback", "commit"}, calls)
+}
+
+func TestTxWatchdog(t *testing.T) {
+	leaks := make(chan OpenTx, 1)
+	SetTxWatchdog(TxWatchdogOptions{
+		Timeout:      50 * time.Millisecond,
+		CaptureStack: true,
+		OnLeak:       func(info OpenTx) { leaks <- info },
+	})
+	defer SetTxWatchdog(TxWatchdogOptions{})
+
+	tx, err := testDB.Begin()
+	assert.NoError(t, err)
+
+	var open *OpenTx
+	for _, info := range OpenTransactions() {
+		if info.ID == tx.ID() {
+			open = &info
+		}
+	}
+	if assert.NotNil(t, open) {
+		assert.Contains(t, open.Stack, "TestTxWatchdog")
+	}
+
+	select {
+	case info := <-leaks:
+		assert.Equal(t, tx.ID(), info.ID)
+		assert.True(t, info.Age >= 50*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("leak was not reported")
+	}
+
+	assert.NoError(t, tx.Rollback())
+	for _, info := range OpenTransactions() {
+		assert.NotEqual(t, tx.ID(), info.ID)
+	}
+}
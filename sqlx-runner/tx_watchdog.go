@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nerdynz/dat/internal/log"
+)
+
+// TxWatchdogOptions configures the detection of leaked transactions, which
+// are transactions neither committed nor rolled back in time.
+type TxWatchdogOptions struct {
+	// Timeout is how long a transaction may be open before it is reported
+	// as leaked. Defaults to 1 minute, a negative value disables reporting.
+	// Open transactions are tracked regardless.
+	Timeout time.Duration
+
+	// CaptureStack records the stack of the goroutine which began each
+	// transaction. It has a cost on every Begin.
+	CaptureStack bool
+
+	// OnLeak is called once for each leaked transaction. Defaults to
+	// logging through the error logger.
+	OnLeak func(OpenTx)
+}
+
+// OpenTx describes a transaction which is neither committed nor rolled
+// back.
+type OpenTx struct {
+	ID    uint64
+	Began time.Time
+	Age   time.Duration
+	// Stack is where the transaction began, if TxWatchdogOptions.CaptureStack
+	// was set.
+	Stack string
+}
+
+type txWatchdog struct {
+	sync.Mutex
+	opts TxWatchdogOptions
+	open map[uint64]*trackedTx
+}
+
+type trackedTx struct {
+	info  OpenTx
+	timer *time.Timer
+}
+
+var lastTxID uint64
+
+var watchdog = &txWatchdog{open: map[uint64]*trackedTx{}}
+
+// SetTxWatchdog configures the detection of leaked transactions. It applies
+// to transactions begun afterwards.
+func SetTxWatchdog(opts TxWatchdogOptions) {
+	watchdog.Lock()
+	defer watchdog.Unlock()
+	watchdog.opts = opts
+}
+
+// OpenTransactions returns the transactions which are currently open, oldest
+// first.
+func OpenTransactions() []OpenTx {
+	watchdog.Lock()
+	defer watchdog.Unlock()
+
+	now := time.Now()
+	txs := make([]OpenTx, 0, len(watchdog.open))
+	for _, tracked := range watchdog.open {
+		info := tracked.info
+		info.Age = now.Sub(info.Began)
+		txs = append(txs, info)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].ID < txs[j].ID
+	})
+	return txs
+}
+
+// track assigns tx its ID and starts watching it.
+func (w *txWatchdog) track(tx *Tx) {
+	tx.id = atomic.AddUint64(&lastTxID, 1)
+
+	w.Lock()
+	defer w.Unlock()
+
+	tracked := &trackedTx{info: OpenTx{ID: tx.id, Began: time.Now()}}
+	if w.opts.CaptureStack {
+		tracked.info.Stack = string(debug.Stack())
+	}
+	timeout := w.opts.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	if timeout > 0 {
+		id := tx.id
+		tracked.timer = time.AfterFunc(timeout, func() { w.report(id) })
+	}
+	w.open[tx.id] = tracked
+}
+
+// untrack stops watching tx once it has ended. It returns false if tx was
+// not watched.
+func (w *txWatchdog) untrack(tx *Tx) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	tracked, ok := w.open[tx.id]
+	if !ok {
+		return false
+	}
+	if tracked.timer != nil {
+		tracked.timer.Stop()
+	}
+	delete(w.open, tx.id)
+	return true
+}
+
+// untrack records that the outermost transaction has ended.
+func (tx *Tx) untrack() {
+	if watchdog.untrack(tx) {
+		tx.counters.txEnded()
+	}
+}
+
+func (w *txWatchdog) report(id uint64) {
+	w.Lock()
+	tracked, ok := w.open[id]
+	onLeak := w.opts.OnLeak
+	w.Unlock()
+	if !ok {
+		return
+	}
+
+	info := tracked.info
+	info.Age = time.Since(info.Began)
+	if onLeak != nil {
+		onLeak(info)
+		return
+	}
+	log.Error("A database transaction was not closed", "id", info.ID, "age", info.Age, "stack", info.Stack)
+}